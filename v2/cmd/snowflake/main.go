@@ -0,0 +1,239 @@
+// Snowflake generates and inspects snowflake IDs.
+//
+// Usage:
+//
+//	snowflake gen [flags]                 generate IDs
+//	snowflake decode [flags] ID...        show the time, node and step of the IDs
+//	snowflake convert [flags] ID...       convert the IDs to another encoding
+//
+// The layout flags (-epoch, -node-bits, -step-bits, -unit) are shared by all the commands.
+// The supported encodings: base2, base10, base32 (Crockford), base36, base58, base62, base64 (URL-safe).
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/CarsonSlovoka/go-pkg/v2/crypto/snowflake"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+type codec struct {
+	encode func(id *snowflake.ID) string
+	parse  func(string) (snowflake.ID, error)
+}
+
+var codecs = map[string]codec{
+	"base2":  {(*snowflake.ID).Base2, snowflake.ParseBase2},
+	"base10": {(*snowflake.ID).String, snowflake.ParseString},
+	"base32": {(*snowflake.ID).Base32, snowflake.ParseBase32},
+	"base36": {(*snowflake.ID).Base36, snowflake.ParseBase36},
+	"base58": {(*snowflake.ID).Base58, snowflake.ParseBase58},
+	"base62": {(*snowflake.ID).Base62, snowflake.ParseBase62},
+	"base64": {(*snowflake.ID).Base64, snowflake.ParseBase64},
+}
+
+func getCodec(name string) (codec, error) {
+	c, ok := codecs[strings.ToLower(name)]
+	if !ok {
+		return codec{}, fmt.Errorf("unknown encoding %q", name)
+	}
+	return c, nil
+}
+
+// config 各個指令共用的參數
+type config struct {
+	epoch    string
+	nodeBits uint
+	stepBits uint
+	unit     time.Duration
+	format   string
+}
+
+func (c *config) bind(fs *flag.FlagSet) {
+	fs.StringVar(&c.epoch, "epoch", "2010-11-04T01:42:54.657Z", "the epoch of the layout, RFC 3339")
+	fs.UintVar(&c.nodeBits, "node-bits", 10, "the number of bits of the node")
+	fs.UintVar(&c.stepBits, "step-bits", 12, "the number of bits of the step")
+	fs.DurationVar(&c.unit, "unit", time.Millisecond, "the time unit of the timestamp")
+	fs.StringVar(&c.format, "format", "text", "output format: text, json")
+}
+
+func (c *config) layout() (snowflake.Layout, error) {
+	epoch, err := time.Parse(time.RFC3339Nano, c.epoch)
+	if err != nil {
+		return snowflake.Layout{}, err
+	}
+	if c.nodeBits > 63 || c.stepBits > 63 {
+		return snowflake.Layout{}, errors.New("node-bits and step-bits must be less than 64")
+	}
+	layout := snowflake.Layout{Epoch: epoch, NodeBits: uint8(c.nodeBits), StepBits: uint8(c.stepBits), Unit: c.unit}
+	return layout, layout.Validate()
+}
+
+func (c *config) output(w io.Writer, text []string, data any) error {
+	switch c.format {
+	case "text":
+		for _, line := range text {
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(data)
+	default:
+		return fmt.Errorf("unknown format %q", c.format)
+	}
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, w io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("usage: snowflake gen|decode|convert [flags] [ID...]")
+	}
+	switch args[0] {
+	case "gen":
+		return runGen(args[1:], w)
+	case "decode":
+		return runDecode(args[1:], w)
+	case "convert":
+		return runConvert(args[1:], w)
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+func runGen(args []string, w io.Writer) error {
+	var (
+		cfg   config
+		node  int64
+		count int
+		enc   string
+	)
+	fs := flag.NewFlagSet("gen", flag.ContinueOnError)
+	cfg.bind(fs)
+	fs.Int64Var(&node, "node", 0, "the node number")
+	fs.IntVar(&count, "n", 1, "how many IDs to generate")
+	fs.StringVar(&enc, "enc", "base10", "the encoding of the output")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	c, err := getCodec(enc)
+	if err != nil {
+		return err
+	}
+	layout, err := cfg.layout()
+	if err != nil {
+		return err
+	}
+	n, err := snowflake.NewNodeWithLayout(node, layout)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]string, 0, count)
+	for _, id := range n.GenerateN(count) {
+		ids = append(ids, c.encode(&id))
+	}
+	return cfg.output(w, ids, ids)
+}
+
+type decoded struct {
+	ID   string    `json:"id"`
+	Time time.Time `json:"time"`
+	Node int64     `json:"node"`
+	Step int64     `json:"step"`
+}
+
+func runDecode(args []string, w io.Writer) error {
+	var (
+		cfg config
+		enc string
+	)
+	fs := flag.NewFlagSet("decode", flag.ContinueOnError)
+	cfg.bind(fs)
+	fs.StringVar(&enc, "enc", "base10", "the encoding of the input")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	c, err := getCodec(enc)
+	if err != nil {
+		return err
+	}
+	layout, err := cfg.layout()
+	if err != nil {
+		return err
+	}
+
+	var (
+		text   []string
+		result []decoded
+	)
+	for _, s := range fs.Args() {
+		id, err := c.parse(s)
+		if err != nil {
+			return err
+		}
+		parts := layout.Decompose(id)
+		text = append(text, fmt.Sprintf("%s\ttime=%s\tnode=%d\tstep=%d", s, parts.Time.Format(time.RFC3339Nano), parts.Node, parts.Step))
+		result = append(result, decoded{s, parts.Time, parts.Node, parts.Step})
+	}
+	return cfg.output(w, text, result)
+}
+
+type converted struct {
+	Input  string `json:"input"`
+	Output string `json:"output"`
+}
+
+func runConvert(args []string, w io.Writer) error {
+	var (
+		cfg      config
+		from, to string
+	)
+	fs := flag.NewFlagSet("convert", flag.ContinueOnError)
+	cfg.bind(fs)
+	fs.StringVar(&from, "from", "base10", "the encoding of the input")
+	fs.StringVar(&to, "to", "base58", "the encoding of the output")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	src, err := getCodec(from)
+	if err != nil {
+		return err
+	}
+	dst, err := getCodec(to)
+	if err != nil {
+		return err
+	}
+
+	var (
+		text   []string
+		result []converted
+	)
+	for _, s := range fs.Args() {
+		id, err := src.parse(s)
+		if err != nil {
+			return err
+		}
+		out := dst.encode(&id)
+		text = append(text, out)
+		result = append(result, converted{s, out})
+	}
+	return cfg.output(w, text, result)
+}
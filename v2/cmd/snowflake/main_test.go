@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestGen(t *testing.T) {
+	var buf bytes.Buffer
+	if err := run([]string{"gen", "-n", "5", "-node", "3", "-enc", "base58"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Fields(buf.String())
+	if len(lines) != 5 {
+		t.Fatal(buf.String())
+	}
+
+	buf.Reset()
+	if err := run([]string{"decode", "-enc", "base58", "-format", "json", lines[0]}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	var result []decoded
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 1 || result[0].Node != 3 || result[0].ID != lines[0] {
+		t.Fatal(buf.String())
+	}
+
+	if err := run([]string{"gen", "-node", "1024"}, &buf); err == nil {
+		t.Fatal("node out of range")
+	}
+}
+
+func TestDecode(t *testing.T) {
+	var buf bytes.Buffer
+	// time=5ms, node=3, step=7
+	if err := run([]string{"decode", "-epoch", "2022-07-01T16:10:54Z", "20983815"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "20983815\ttime=2022-07-01T16:10:54.005Z\tnode=3\tstep=7\n"; buf.String() != expected {
+		t.Fatalf("%q", buf.String())
+	}
+
+	buf.Reset()
+	if err := run([]string{"decode", "-unit", "10ms", "-node-bits", "16", "-step-bits", "8", "-epoch", "2022-07-01T16:10:54Z", "16777985"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "16777985\ttime=2022-07-01T16:10:54.01Z\tnode=3\tstep=1\n"; buf.String() != expected {
+		t.Fatalf("%q", buf.String())
+	}
+
+	for _, args := range [][]string{
+		{"decode", "abc"},
+		{"decode", "-enc", "base99", "1"},
+		{"decode", "-node-bits", "60", "1"},
+		{"decode", "-format", "xml", "1"},
+		{"unknown"},
+		{},
+	} {
+		if err := run(args, &buf); err == nil {
+			t.Fatal(args)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	var buf bytes.Buffer
+	if err := run([]string{"convert", "-to", "base62", "1234567890123456789"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "1TCKi1nFuNh\n" {
+		t.Fatalf("%q", buf.String())
+	}
+
+	buf.Reset()
+	if err := run([]string{"convert", "-from", "base62", "-to", "base10", "-format", "json", "1TCKi1nFuNh"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	var result []converted
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result[0].Output != "1234567890123456789" {
+		t.Fatal(result)
+	}
+}
@@ -0,0 +1,139 @@
+package rand
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+var (
+	ErrEmpty        = errors.New("empty slice")
+	ErrInvalidRange = errors.New("invalid range")
+)
+
+// randIntN returns an unbiased random number in [0, n), n must be positive
+func randIntN(r io.Reader, n uint64) (uint64, error) {
+	// 丟掉最後不完整的那一段，剩下的取餘數就不會有偏差
+	limit := math.MaxUint64 - math.MaxUint64%n
+	var b [8]byte
+	for {
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return 0, err
+		}
+		if v := binary.BigEndian.Uint64(b[:]); v < limit {
+			return v % n, nil
+		}
+	}
+}
+
+// IntN returns a random number in [0, n) without modulo bias.
+// The random bytes come from crypto/rand unless src is given, so do the other functions in this file.
+func IntN(n int, src ...io.Reader) (int, error) {
+	if n <= 0 {
+		return 0, fmt.Errorf("%w: n must be positive, got %d", ErrInvalidRange, n)
+	}
+	v, err := randIntN(source(src), uint64(n))
+	return int(v), err
+}
+
+// Int64Range returns a random number in [low, high], both inclusive
+func Int64Range(low, high int64, src ...io.Reader) (int64, error) {
+	if low > high {
+		return 0, fmt.Errorf("%w: low %d > high %d", ErrInvalidRange, low, high)
+	}
+	r := source(src)
+	span := uint64(high-low) + 1
+	if span == 0 { // [MinInt64, MaxInt64] 全部的數字都可以
+		var b [8]byte
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return 0, err
+		}
+		return int64(binary.BigEndian.Uint64(b[:])), nil
+	}
+	v, err := randIntN(r, span)
+	if err != nil {
+		return 0, err
+	}
+	return low + int64(v), nil
+}
+
+// Shuffle shuffles s in place (Fisher-Yates)
+func Shuffle[T any](s []T, src ...io.Reader) error {
+	r := source(src)
+	for i := len(s) - 1; i > 0; i-- {
+		j, err := randIntN(r, uint64(i+1))
+		if err != nil {
+			return err
+		}
+		s[i], s[j] = s[j], s[i]
+	}
+	return nil
+}
+
+// Choice returns a random element of s
+func Choice[T any](s []T, src ...io.Reader) (T, error) {
+	var zero T
+	if len(s) == 0 {
+		return zero, ErrEmpty
+	}
+	i, err := randIntN(source(src), uint64(len(s)))
+	if err != nil {
+		return zero, err
+	}
+	return s[i], nil
+}
+
+// Sample returns k distinct elements of s (without replacement), in random order. s is not modified.
+func Sample[T any](s []T, k int, src ...io.Reader) ([]T, error) {
+	if k < 0 || k > len(s) {
+		return nil, fmt.Errorf("%w: k must be between 0 and %d, got %d", ErrInvalidRange, len(s), k)
+	}
+	r := source(src)
+	pool := make([]T, len(s))
+	copy(pool, s)
+	for i := 0; i < k; i++ { // 只需要做前k個的Fisher-Yates
+		j, err := randIntN(r, uint64(len(pool)-i))
+		if err != nil {
+			return nil, err
+		}
+		j += uint64(i)
+		pool[i], pool[j] = pool[j], pool[i]
+	}
+	return pool[:k:k], nil
+}
+
+// WeightedChoice returns items[i] with the probability weights[i] / sum(weights).
+// The weights are integers so the result is exact, the sum must be positive.
+func WeightedChoice[T any](items []T, weights []uint64, src ...io.Reader) (T, error) {
+	var zero T
+	if len(items) == 0 {
+		return zero, ErrEmpty
+	}
+	if len(items) != len(weights) {
+		return zero, fmt.Errorf("%w: %d items but %d weights", ErrInvalidRange, len(items), len(weights))
+	}
+	var total uint64
+	for _, w := range weights {
+		if total+w < total {
+			return zero, fmt.Errorf("%w: the sum of the weights overflows", ErrInvalidRange)
+		}
+		total += w
+	}
+	if total == 0 {
+		return zero, fmt.Errorf("%w: the sum of the weights must be positive", ErrInvalidRange)
+	}
+
+	v, err := randIntN(source(src), total)
+	if err != nil {
+		return zero, err
+	}
+	for i, w := range weights {
+		if v < w {
+			return items[i], nil
+		}
+		v -= w
+	}
+	return items[len(items)-1], nil // 不會到這裡
+}
@@ -0,0 +1,150 @@
+package rand
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"sort"
+	"testing"
+)
+
+// uint64Reader 依序吐出指定的數值，讓測試可以預期結果
+func uint64Reader(values ...uint64) *bytes.Reader {
+	buf := make([]byte, 8*len(values))
+	for i, v := range values {
+		binary.BigEndian.PutUint64(buf[i*8:], v)
+	}
+	return bytes.NewReader(buf)
+}
+
+func TestIntN(t *testing.T) {
+	// 最後不完整的那一段會被丟掉
+	v, err := IntN(10, uint64Reader(math.MaxUint64, 23))
+	if err != nil || v != 3 {
+		t.Fatal(v, err)
+	}
+	if _, err = IntN(0); !errors.Is(err, ErrInvalidRange) {
+		t.Fatal(err)
+	}
+	if _, err = IntN(10, bytes.NewReader(nil)); err == nil {
+		t.Fatal("reader error")
+	}
+
+	counts := make([]int, 3)
+	for i := 0; i < 30000; i++ {
+		v, _ = IntN(3)
+		counts[v]++
+	}
+	for _, c := range counts {
+		if c < 9400 || c > 10600 {
+			t.Fatal(counts)
+		}
+	}
+}
+
+func TestInt64Range(t *testing.T) {
+	if v, err := Int64Range(-5, 5, uint64Reader(12)); err != nil || v != -4 {
+		t.Fatal(v, err)
+	}
+	if v, _ := Int64Range(7, 7); v != 7 {
+		t.Fatal(v)
+	}
+	if v, err := Int64Range(math.MinInt64, math.MaxInt64, uint64Reader(0)); err != nil || v != 0 {
+		t.Fatal(v, err)
+	}
+	if v, err := Int64Range(math.MinInt64, 0, uint64Reader(0)); err != nil || v != math.MinInt64 {
+		t.Fatal(v, err)
+	}
+	if _, err := Int64Range(1, 0); !errors.Is(err, ErrInvalidRange) {
+		t.Fatal(err)
+	}
+}
+
+func TestShuffle(t *testing.T) {
+	s := []string{"a", "b", "c", "d"}
+	// i=3: j=0, i=2: j=2, i=1: j=1
+	if err := Shuffle(s, uint64Reader(0, 2, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if s[0] != "d" || s[3] != "a" || s[1] != "b" || s[2] != "c" {
+		t.Fatal(s)
+	}
+
+	n := make([]int, 100)
+	for i := range n {
+		n[i] = i
+	}
+	_ = Shuffle(n)
+	if sort.IntsAreSorted(n) {
+		t.Fatal("not shuffled")
+	}
+	sort.Ints(n)
+	for i := range n {
+		if n[i] != i {
+			t.Fatal("element lost")
+		}
+	}
+}
+
+func TestChoiceAndSample(t *testing.T) {
+	s := []int{10, 20, 30}
+	if v, err := Choice(s, uint64Reader(2)); err != nil || v != 30 {
+		t.Fatal(v, err)
+	}
+	if _, err := Choice([]int{}); !errors.Is(err, ErrEmpty) {
+		t.Fatal(err)
+	}
+
+	got, err := Sample(s, 2, uint64Reader(2, 0))
+	if err != nil || len(got) != 2 || got[0] != 30 || got[1] != 20 {
+		t.Fatal(got, err)
+	}
+	if s[0] != 10 || s[2] != 30 {
+		t.Fatal("s must not be modified")
+	}
+	if got, _ = Sample(s, 3); len(got) != 3 || got[0]+got[1]+got[2] != 60 {
+		t.Fatal(got)
+	}
+	if got, _ = Sample(s, 0); len(got) != 0 {
+		t.Fatal(got)
+	}
+	for _, k := range []int{-1, 4} {
+		if _, err = Sample(s, k); !errors.Is(err, ErrInvalidRange) {
+			t.Fatal(k, err)
+		}
+	}
+}
+
+func TestWeightedChoice(t *testing.T) {
+	items := []string{"a", "b", "c"}
+	weights := []uint64{1, 0, 3}
+	for v, expected := range map[uint64]string{0: "a", 1: "c", 3: "c"} {
+		if got, err := WeightedChoice(items, weights, uint64Reader(v)); err != nil || got != expected {
+			t.Fatal(v, got, err)
+		}
+	}
+
+	counts := map[string]int{}
+	for i := 0; i < 20000; i++ {
+		v, _ := WeightedChoice(items, weights)
+		counts[v]++
+	}
+	if counts["b"] != 0 || counts["a"] < 4500 || counts["a"] > 5500 {
+		t.Fatal(counts)
+	}
+
+	for _, d := range []struct {
+		items   []string
+		weights []uint64
+	}{
+		{nil, nil},
+		{items, []uint64{1, 2}},
+		{items, []uint64{0, 0, 0}},
+		{items, []uint64{math.MaxUint64, 1, 0}},
+	} {
+		if _, err := WeightedChoice(d.items, d.weights); err == nil {
+			t.Fatal(d)
+		}
+	}
+}
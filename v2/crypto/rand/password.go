@@ -0,0 +1,244 @@
+package rand
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+const (
+	charsLower     = "abcdefghijklmnopqrstuvwxyz"
+	charsUpper     = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	charsDigits    = "0123456789"
+	DefaultSymbols = "!#$%&*+-=?@^_~"
+	Ambiguous      = "0Oo1lI|`'\"" // 容易看錯的字元
+)
+
+var ErrInvalidPolicy = errors.New("invalid password policy")
+
+// PasswordPolicy describes the rules of NewPassword
+type PasswordPolicy struct {
+	Length int
+
+	// 各類字元最少要出現幾次，-1表示完全不使用這一類
+	Lower   int
+	Upper   int
+	Digits  int
+	Symbols int
+
+	SymbolSet        string // 可以使用的符號，空字串表示DefaultSymbols
+	ExcludeAmbiguous bool   // 不使用 Ambiguous 之中的字元 (0/O, l/1, ...)
+	MaxRun           int    // 同一個字元最多可以連續出現幾次，0表示不限制
+}
+
+// DefaultPasswordPolicy 16個字元，每一類至少一個，沒有容易看錯的字元，同一個字元不會連續出現
+var DefaultPasswordPolicy = PasswordPolicy{
+	Length: 16, Lower: 1, Upper: 1, Digits: 1, Symbols: 1,
+	ExcludeAmbiguous: true,
+	MaxRun:           1,
+}
+
+// classes 回傳每一類可用的字元以及最少數量 (不使用的類別不會回傳)
+func (p PasswordPolicy) classes() (sets []string, mins []int) {
+	symbols := p.SymbolSet
+	if symbols == "" {
+		symbols = DefaultSymbols
+	}
+	for i, set := range []string{charsLower, charsUpper, charsDigits, symbols} {
+		minCount := []int{p.Lower, p.Upper, p.Digits, p.Symbols}[i]
+		if minCount < 0 {
+			continue
+		}
+		if p.ExcludeAmbiguous {
+			set = strings.Map(func(r rune) rune {
+				if strings.ContainsRune(Ambiguous, r) {
+					return -1
+				}
+				return r
+			}, set)
+		}
+		sets = append(sets, set)
+		mins = append(mins, minCount)
+	}
+	return
+}
+
+// Validate reports an error if no password can satisfy the policy
+func (p PasswordPolicy) Validate() error {
+	if p.Length <= 0 {
+		return fmt.Errorf("%w: the length must be positive", ErrInvalidPolicy)
+	}
+	if p.MaxRun < 0 {
+		return fmt.Errorf("%w: MaxRun must not be negative", ErrInvalidPolicy)
+	}
+	sets, mins := p.classes()
+	if len(sets) == 0 {
+		return fmt.Errorf("%w: all character classes are disabled", ErrInvalidPolicy)
+	}
+	total := 0
+	for i, set := range sets {
+		total += mins[i]
+		if len(set) == 0 {
+			return fmt.Errorf("%w: a character class is empty", ErrInvalidPolicy)
+		}
+	}
+	if total > p.Length {
+		return fmt.Errorf("%w: the minimum counts (%d) exceed the length (%d)", ErrInvalidPolicy, total, p.Length)
+	}
+	if err := checkAlphabet(strings.Join(sets, "")); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidPolicy, err)
+	}
+	if p.MaxRun == 1 && len(strings.Join(sets, "")) < 2 {
+		return fmt.Errorf("%w: at least 2 characters are needed when MaxRun is 1", ErrInvalidPolicy)
+	}
+	if p.MaxRun > 0 {
+		for i, set := range sets {
+			// 只有一個字元的類別，每MaxRun個之間至少要有一個其它的字元
+			if len(set) == 1 && len(sets) > 1 && mins[i] > (p.Length-mins[i]+1)*p.MaxRun {
+				return fmt.Errorf("%w: %d of %q can't be separated within MaxRun", ErrInvalidPolicy, mins[i], set)
+			}
+		}
+	}
+	return nil
+}
+
+// Entropy returns an estimate of the password strength in bits: Length * log2(the number of the usable characters).
+// The minimum counts make it a little lower in fact.
+func (p PasswordPolicy) Entropy() float64 {
+	sets, _ := p.classes()
+	return float64(p.Length) * math.Log2(float64(len(strings.Join(sets, ""))))
+}
+
+// NewPassword returns a password which satisfies the policy. The random bytes come from crypto/rand unless src is given.
+func NewPassword(policy PasswordPolicy, src ...io.Reader) (string, error) {
+	if err := policy.Validate(); err != nil {
+		return "", err
+	}
+	r := source(src)
+	sets, mins := policy.classes()
+	pool := strings.Join(sets, "")
+
+	// slots 每個位置要從哪個集合選字元: 先放每一類的最少數量，其它的從pool選
+	slots := make([]string, 0, policy.Length)
+	var single string // 只有一個字元的類別(例如SymbolSet只有一個符號)，要安排位置才能滿足MaxRun
+	for i, set := range sets {
+		if len(set) == 1 && len(sets) > 1 && policy.MaxRun > 0 {
+			single = set
+			pool = strings.ReplaceAll(pool, set, "") // 只出現在最少數量的位置，才能計算需要的間隔
+		}
+		for j := 0; j < mins[i]; j++ {
+			slots = append(slots, set)
+		}
+	}
+	for len(slots) < policy.Length {
+		slots = append(slots, pool)
+	}
+	if err := Shuffle(slots, r); err != nil {
+		return "", err
+	}
+
+	buf := make([]byte, 0, policy.Length)
+	run := 0 // buf結尾的字元連續出現的次數
+	for i := range slots {
+		if single != "" {
+			if err := arrangeSingle(r, slots[i:], single, buf, run, policy.MaxRun); err != nil {
+				return "", err
+			}
+		}
+		set := slots[i]
+		if policy.MaxRun > 0 && run == policy.MaxRun { // 不能再接上前一個字元
+			set = strings.ReplaceAll(set, string(buf[i-1]), "")
+		}
+		c, err := pick(r, set)
+		if err != nil {
+			return "", err
+		}
+		if i > 0 && c == buf[i-1] {
+			run++
+		} else {
+			run = 1
+		}
+		buf = append(buf, c)
+	}
+	return string(buf), nil
+}
+
+// arrangeSingle 決定slots[0]是否必須是(或不能是)single，必要的時候與後面的位置交換。
+// 剩下k個single，o個其它的位置，每個其它的位置後面最多接maxRun個single，
+// 所以只要維持 k <= (maxRun - 目前連續的single) + o*maxRun 就一定排得出來
+func arrangeSingle(r io.Reader, slots []string, single string, buf []byte, run, maxRun int) error {
+	k := 0
+	for _, s := range slots {
+		if s == single {
+			k++
+		}
+	}
+	o := len(slots) - k
+	if len(buf) == 0 || buf[len(buf)-1] != single[0] {
+		run = 0
+	}
+	var want bool // slots[0]應該是single
+	switch {
+	case run == maxRun:
+		want = false
+	case k > (o-1)*maxRun: // 這裡放其它的字元就排不下了
+		want = true
+	default:
+		return nil
+	}
+	if (slots[0] == single) == want {
+		return nil
+	}
+	var candidates []int
+	for j := 1; j < len(slots); j++ {
+		if (slots[j] == single) == want {
+			candidates = append(candidates, j)
+		}
+	}
+	j, err := randIntN(r, uint64(len(candidates)))
+	if err != nil {
+		return err
+	}
+	slots[0], slots[candidates[j]] = slots[candidates[j]], slots[0]
+	return nil
+}
+
+func pick(r io.Reader, set string) (byte, error) {
+	i, err := randIntN(r, uint64(len(set)))
+	if err != nil {
+		return 0, err
+	}
+	return set[i], nil
+}
+
+//go:embed wordlist.txt
+var wordListRaw string
+
+// WordList is the embedded list of 1296 (6^4) common English words used by NewPassphrase, each word can be picked by 4 dice.
+var WordList = strings.Fields(wordListRaw)
+
+// NewPassphrase returns numWords words picked from WordList, joined by the separator.
+// The random bytes come from crypto/rand unless src is given.
+func NewPassphrase(numWords int, separator string, src ...io.Reader) (string, error) {
+	if numWords <= 0 {
+		return "", fmt.Errorf("%w: numWords must be positive, got %d", ErrInvalidLength, numWords)
+	}
+	r := source(src)
+	words := make([]string, numWords)
+	for i := range words {
+		idx, err := randIntN(r, uint64(len(WordList)))
+		if err != nil {
+			return "", err
+		}
+		words[i] = WordList[idx]
+	}
+	return strings.Join(words, separator), nil
+}
+
+// PassphraseEntropy returns the strength in bits of a passphrase with numWords words: numWords * log2(len(WordList))
+func PassphraseEntropy(numWords int) float64 {
+	return float64(numWords) * math.Log2(float64(len(WordList)))
+}
@@ -0,0 +1,141 @@
+package rand
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func ExampleNewPassphrase() {
+	s, _ := NewPassphrase(6, "-")
+	fmt.Println(len(strings.Split(s, "-")), math.Round(PassphraseEntropy(6)))
+	// Output: 6 62
+}
+
+func countIn(s, set string) (n int) {
+	for _, c := range s {
+		if strings.ContainsRune(set, c) {
+			n++
+		}
+	}
+	return
+}
+
+// maxRun 最長的連續相同字元
+func maxRun(b []byte) int {
+	longest, run := 0, 0
+	for i := range b {
+		if i > 0 && b[i] == b[i-1] {
+			run++
+		} else {
+			run = 1
+		}
+		if run > longest {
+			longest = run
+		}
+	}
+	return longest
+}
+
+func TestNewPassword(t *testing.T) {
+	for i := 0; i < 200; i++ {
+		pw, err := NewPassword(DefaultPasswordPolicy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pw) != 16 || countIn(pw, charsLower) < 1 || countIn(pw, charsUpper) < 1 ||
+			countIn(pw, charsDigits) < 1 || countIn(pw, DefaultSymbols) < 1 {
+			t.Fatal(pw)
+		}
+		if countIn(pw, Ambiguous) != 0 || maxRun([]byte(pw)) > 1 {
+			t.Fatal(pw)
+		}
+	}
+
+	policy := PasswordPolicy{Length: 12, Lower: -1, Upper: -1, Digits: 8, Symbols: 2, SymbolSet: "#@"}
+	for i := 0; i < 100; i++ {
+		pw, err := NewPassword(policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if countIn(pw, charsDigits) < 8 || countIn(pw, "#@") < 2 || countIn(pw, charsDigits+"#@") != 12 {
+			t.Fatal(pw)
+		}
+	}
+}
+
+func TestNewPassword_MaxRun(t *testing.T) {
+	for _, policy := range []PasswordPolicy{
+		{Length: 64, Digits: 1, Lower: -1, Upper: -1, Symbols: -1, MaxRun: 1},
+		{Length: 100, Digits: 1, Lower: -1, Upper: -1, Symbols: -1, MaxRun: 1, ExcludeAmbiguous: true},
+		// 只有一個字元的類別，要剛好間隔排列: #a#a#
+		{Length: 5, Lower: 2, Upper: -1, Digits: -1, Symbols: 3, SymbolSet: "#", MaxRun: 1},
+		{Length: 20, Lower: 1, Upper: -1, Digits: -1, Symbols: 12, SymbolSet: "#", MaxRun: 2},
+		{Length: 30, Lower: 0, Upper: 0, Digits: 0, Symbols: 1, SymbolSet: "#", MaxRun: 1},
+	} {
+		for i := 0; i < 200; i++ {
+			pw, err := NewPassword(policy)
+			if err != nil {
+				t.Fatalf("%+v: %v", policy, err)
+			}
+			if len(pw) != policy.Length || maxRun([]byte(pw)) > policy.MaxRun || countIn(pw, "#") < policy.Symbols {
+				t.Fatalf("%+v: %s", policy, pw)
+			}
+		}
+	}
+}
+
+func TestPasswordPolicy_Validate(t *testing.T) {
+	for _, p := range []PasswordPolicy{
+		{Length: 0},
+		{Length: 4, Lower: 2, Upper: 2, Digits: 1},
+		{Length: 8, Lower: -1, Upper: -1, Digits: -1, Symbols: -1},
+		{Length: 8, MaxRun: -1},
+		{Length: 8, SymbolSet: "!!"},
+		{Length: 8, SymbolSet: "a"}, // 與小寫重複
+		{Length: 8, Lower: -1, Upper: -1, Digits: -1, SymbolSet: "#", MaxRun: 1},
+		{Length: 8, Lower: 1, Upper: -1, Digits: -1, Symbols: 7, SymbolSet: "#", MaxRun: 2}, // 只有一個間隔: ##a##
+	} {
+		if _, err := NewPassword(p); !errors.Is(err, ErrInvalidPolicy) {
+			t.Fatalf("%+v: %v", p, err)
+		}
+	}
+	if err := (PasswordPolicy{Length: 8, Lower: -1, Upper: -1, Symbols: -1, Digits: 1, MaxRun: 1}).Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPasswordPolicy_Entropy(t *testing.T) {
+	p := PasswordPolicy{Length: 10, Upper: -1, Digits: -1, Symbols: -1}
+	if e := p.Entropy(); math.Abs(e-10*math.Log2(26)) > 1e-9 {
+		t.Fatal(e)
+	}
+}
+
+func TestNewPassphrase(t *testing.T) {
+	if len(WordList) != 1296 {
+		t.Fatal(len(WordList))
+	}
+	seen := map[string]bool{}
+	for _, w := range WordList {
+		if seen[w] {
+			t.Fatal("duplicate word", w)
+		}
+		seen[w] = true
+	}
+
+	s, err := NewPassphrase(5, " ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, w := range strings.Split(s, " ") {
+		if !seen[w] {
+			t.Fatal(w)
+		}
+	}
+	if _, err = NewPassphrase(0, " "); !errors.Is(err, ErrInvalidLength) {
+		t.Fatal(err)
+	}
+}
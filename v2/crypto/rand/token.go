@@ -0,0 +1,155 @@
+package rand
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"math/bits"
+	"strings"
+)
+
+// 常用的字母表
+const (
+	AlphabetDigits       = "0123456789"
+	AlphabetHex          = "0123456789abcdef"
+	AlphabetAlphanumeric = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	AlphabetBase58       = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+	AlphabetNanoID       = "useandom-26T198340PX75pxJACKVERYMINDBUSHWOLF_GQZbfghjklqvwyzrict" // the URL-safe alphabet of NanoID
+)
+
+var (
+	ErrInvalidAlphabet = errors.New("invalid alphabet")
+	ErrInvalidLength   = errors.New("invalid length")
+	ErrInvalidAPIKey   = errors.New("invalid API key")
+)
+
+// source 沒有指定就用crypto/rand
+func source(src []io.Reader) io.Reader {
+	if len(src) > 0 && src[0] != nil {
+		return src[0]
+	}
+	return rand.Reader
+}
+
+func checkAlphabet(alphabet string) error {
+	if len(alphabet) < 2 || len(alphabet) > 256 {
+		return fmt.Errorf("%w: the length must be between 2 and 256, got %d", ErrInvalidAlphabet, len(alphabet))
+	}
+	var seen [256]bool
+	for i := 0; i < len(alphabet); i++ {
+		if seen[alphabet[i]] {
+			return fmt.Errorf("%w: duplicate character %q", ErrInvalidAlphabet, alphabet[i])
+		}
+		seen[alphabet[i]] = true
+	}
+	return nil
+}
+
+// String returns a random string of length characters picked from the alphabet (at most 256 unique bytes).
+// Every character has the same probability: the random bytes are masked to the smallest power of 2 that can hold the alphabet,
+// the values out of the alphabet are thrown away (rejection sampling), instead of taking the modulo which is biased.
+// The random bytes come from crypto/rand unless src is given.
+func String(length int, alphabet string, src ...io.Reader) (string, error) {
+	if err := checkAlphabet(alphabet); err != nil {
+		return "", err
+	}
+	if length <= 0 {
+		return "", fmt.Errorf("%w: %d", ErrInvalidLength, length)
+	}
+
+	r := source(src)
+	mask := byte(1<<bits.Len8(uint8(len(alphabet)-1)) - 1)
+	// 與NanoID相同，一次讀取預計夠用的量，1.6倍是為了彌補被丟掉的部份
+	step := int(math.Ceil(1.6 * float64(int(mask)*length) / float64(len(alphabet))))
+	buf := make([]byte, step)
+	out := make([]byte, 0, length)
+	for {
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return "", err
+		}
+		for _, b := range buf {
+			if idx := int(b & mask); idx < len(alphabet) {
+				out = append(out, alphabet[idx])
+				if len(out) == length {
+					return string(out), nil
+				}
+			}
+		}
+	}
+}
+
+// NanoID returns a 21 characters NanoID, the same as nanoid() of https://github.com/ai/nanoid
+func NanoID(src ...io.Reader) (string, error) {
+	return String(21, AlphabetNanoID, src...)
+}
+
+const apiKeyChecksumLen = 6
+
+// NewAPIKey returns "{prefix}_{random}{checksum}", for example: sk_live_3ZfX...aB12cD
+// The random part is length alphanumeric characters, the checksum is the CRC32 of everything before it, in 6 base62 characters.
+// The checksum is not a secret, it lets CheckAPIKey find typos offline without asking the database.
+// The prefix may only contain letters, digits and underscores, and must not be empty.
+func NewAPIKey(prefix string, length int, src ...io.Reader) (string, error) {
+	if err := checkAPIKeyPrefix(prefix); err != nil {
+		return "", err
+	}
+	if length < 16 {
+		return "", fmt.Errorf("%w: the random part of an API key needs at least 16 characters, got %d", ErrInvalidLength, length)
+	}
+	body, err := String(length, AlphabetAlphanumeric, src...)
+	if err != nil {
+		return "", err
+	}
+	key := prefix + "_" + body
+	return key + apiKeyChecksum(key), nil
+}
+
+// CheckAPIKey reports an error if the key doesn't have the prefix or the checksum doesn't match
+func CheckAPIKey(key, prefix string) error {
+	if err := checkAPIKeyPrefix(prefix); err != nil {
+		return err
+	}
+	if !strings.HasPrefix(key, prefix+"_") {
+		return fmt.Errorf("%w: the prefix must be %q", ErrInvalidAPIKey, prefix)
+	}
+	body := key[len(prefix)+1:]
+	if len(body) <= apiKeyChecksumLen {
+		return fmt.Errorf("%w: too short", ErrInvalidAPIKey)
+	}
+	for i := 0; i < len(body); i++ {
+		if strings.IndexByte(AlphabetAlphanumeric, body[i]) < 0 {
+			return fmt.Errorf("%w: invalid character %q", ErrInvalidAPIKey, body[i])
+		}
+	}
+	n := len(key) - apiKeyChecksumLen
+	if apiKeyChecksum(key[:n]) != key[n:] {
+		return fmt.Errorf("%w: checksum mismatch", ErrInvalidAPIKey)
+	}
+	return nil
+}
+
+func checkAPIKeyPrefix(prefix string) error {
+	if prefix == "" {
+		return fmt.Errorf("%w: empty prefix", ErrInvalidAPIKey)
+	}
+	for _, c := range prefix {
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '_') {
+			return fmt.Errorf("%w: invalid character %q in prefix", ErrInvalidAPIKey, c)
+		}
+	}
+	return nil
+}
+
+// apiKeyChecksum CRC32以base62表示，固定6碼 (62^6 > 2^32)
+func apiKeyChecksum(s string) string {
+	v := crc32.ChecksumIEEE([]byte(s))
+	var buf [apiKeyChecksumLen]byte
+	for i := len(buf) - 1; i >= 0; i-- {
+		buf[i] = AlphabetAlphanumeric[v%62]
+		v /= 62
+	}
+	return string(buf[:])
+}
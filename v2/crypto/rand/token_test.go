@@ -0,0 +1,133 @@
+package rand
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func ExampleNewAPIKey() {
+	key, _ := NewAPIKey("sk_live", 32)
+	fmt.Println(len(key), CheckAPIKey(key, "sk_live"))
+	// Output: 46 <nil>
+}
+
+func TestString(t *testing.T) {
+	// mask = 3, 所以3會被丟掉; 高位元也會被遮掉: 0x41 & 3 = 1
+	s, err := String(5, "abc", bytes.NewReader([]byte{0, 3, 1, 3, 2, 0x41, 0xFF, 0}))
+	if err != nil || s != "abcba" {
+		t.Fatal(s, err)
+	}
+
+	for _, d := range []struct {
+		length   int
+		alphabet string
+		err      error
+	}{
+		{10, "", ErrInvalidAlphabet},
+		{10, "a", ErrInvalidAlphabet},
+		{10, "abca", ErrInvalidAlphabet},
+		{10, strings.Repeat("ab", 129), ErrInvalidAlphabet},
+		{0, "ab", ErrInvalidLength},
+		{-1, "ab", ErrInvalidLength},
+	} {
+		if _, err = String(d.length, d.alphabet); !errors.Is(err, d.err) {
+			t.Fatal(d, err)
+		}
+	}
+
+	// 全部256個字元也可以
+	var all []byte
+	for i := 0; i < 256; i++ {
+		all = append(all, byte(i))
+	}
+	if s, err = String(100, string(all)); err != nil || len(s) != 100 {
+		t.Fatal(err)
+	}
+
+	if _, err = String(10, "ab", bytes.NewReader(nil)); err == nil {
+		t.Fatal("reader error")
+	}
+}
+
+// 粗略檢查分佈是否平均: 如果用取餘數的方式，前面的字元會多出約1/6
+func TestStringUniform(t *testing.T) {
+	const alphabet = "abcdef" // mask 7, 6和7會被丟掉
+	s, err := String(60000, alphabet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range alphabet {
+		if n := strings.Count(s, string(c)); n < 9400 || n > 10600 {
+			t.Fatalf("%c: %d", c, n)
+		}
+	}
+}
+
+func TestNanoID(t *testing.T) {
+	id, err := NanoID()
+	if err != nil || len(id) != 21 {
+		t.Fatal(id, err)
+	}
+	for _, c := range id {
+		if !strings.ContainsRune(AlphabetNanoID, c) {
+			t.Fatal(id)
+		}
+	}
+	// 64個字元 => 每個byte只取低6位元，與nanoid相同
+	if id, _ = NanoID(bytes.NewReader(bytes.Repeat([]byte{0x40, 0x01}, 20))); id != strings.Repeat("us", 10)+"u" {
+		t.Fatal(id)
+	}
+}
+
+func TestAPIKey(t *testing.T) {
+	key, err := NewAPIKey("ghp", 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(key, "ghp_") || len(key) != 4+30+6 {
+		t.Fatal(key)
+	}
+	if err = CheckAPIKey(key, "ghp"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 打錯一個字
+	typo := []byte(key)
+	if typo[10] == 'a' {
+		typo[10] = 'b'
+	} else {
+		typo[10] = 'a'
+	}
+	for _, d := range []struct {
+		key    string
+		prefix string
+	}{
+		{string(typo), "ghp"},
+		{key, "sk"},
+		{key[:len(key)-1], "ghp"},
+		{key + "x", "ghp"},
+		{"ghp_abc", "ghp"},
+		{"ghp_" + strings.Repeat("-", 20), "ghp"},
+		{key, ""},
+	} {
+		if err = CheckAPIKey(d.key, d.prefix); !errors.Is(err, ErrInvalidAPIKey) {
+			t.Fatal(d, err)
+		}
+	}
+
+	for _, d := range []struct {
+		prefix string
+		length int
+	}{
+		{"", 32},
+		{"sk-live", 32},
+		{"sk", 15},
+	} {
+		if _, err = NewAPIKey(d.prefix, d.length); err == nil {
+			t.Fatal(d)
+		}
+	}
+}
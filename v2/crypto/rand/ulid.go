@@ -0,0 +1,229 @@
+package rand
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalidULID       = errors.New("invalid ULID")
+	ErrULIDTime          = errors.New("the time of ULID must be between 1970-01-01 and 10889-08-02")
+	ErrMonotonicOverflow = errors.New("ULID monotonic entropy overflow")
+)
+
+// ULID https://github.com/ulid/spec
+// 48 bits unix timestamp in milliseconds + 80 bits randomness, encoded in 26 characters of Crockford base32.
+type ULID [16]byte
+
+const (
+	ulidEncoding = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+	ulidMaxTime  = 1<<48 - 1
+)
+
+var ulidDecoding [256]byte
+
+func init() {
+	for i := range ulidDecoding {
+		ulidDecoding[i] = 0xFF
+	}
+	for i := 0; i < len(ulidEncoding); i++ {
+		c := ulidEncoding[i]
+		ulidDecoding[c] = byte(i)
+		if 'A' <= c && c <= 'Z' {
+			ulidDecoding[c+'a'-'A'] = byte(i)
+		}
+	}
+}
+
+// MonotonicReader is an entropy source for ULID which knows the millisecond.
+// MonotonicRead returns the millisecond that the entropy belongs to,
+// it's larger than ms when the clock has moved backwards, and NewULIDAt uses it as the timestamp.
+type MonotonicReader interface {
+	io.Reader
+	MonotonicRead(ms uint64, p []byte) (uint64, error)
+}
+
+// MonotonicEntropy makes the ULIDs in the same millisecond increase:
+// the first one is random, the following ones are the previous entropy plus one.
+// It's safe for concurrent use.
+type MonotonicEntropy struct {
+	mutex  sync.Mutex
+	r      io.Reader
+	lastMs uint64
+	last   [10]byte
+	inited bool
+}
+
+// NewMonotonicEntropy returns a MonotonicEntropy reading from r, crypto/rand.Reader if r is nil
+func NewMonotonicEntropy(r io.Reader) *MonotonicEntropy {
+	if r == nil {
+		r = rand.Reader
+	}
+	return &MonotonicEntropy{r: r}
+}
+
+// Read reads from the underlying reader
+func (m *MonotonicEntropy) Read(p []byte) (int, error) {
+	return m.r.Read(p)
+}
+
+// MonotonicRead writes the 10 bytes entropy for ms into p.
+// The clock moving backwards is treated as the last millisecond, which is returned, so the ULIDs never decrease.
+func (m *MonotonicEntropy) MonotonicRead(ms uint64, p []byte) (uint64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.inited && ms <= m.lastMs {
+		for i := len(m.last) - 1; i >= 0; i-- { // 80 bits + 1
+			m.last[i]++
+			if m.last[i] != 0 {
+				copy(p, m.last[:])
+				return m.lastMs, nil
+			}
+		}
+		for i := range m.last { // 維持在最大值，直到下一個毫秒才重新取亂數
+			m.last[i] = 0xFF
+		}
+		return 0, ErrMonotonicOverflow
+	}
+
+	if _, err := io.ReadFull(m.r, m.last[:]); err != nil {
+		return 0, err
+	}
+	m.lastMs = ms
+	m.inited = true
+	copy(p, m.last[:])
+	return ms, nil
+}
+
+var defaultEntropy = NewMonotonicEntropy(nil)
+
+// NewULID returns a ULID of the current time. The randomness comes from crypto/rand,
+// and the ULIDs created in the same millisecond are monotonic.
+func NewULID() (ULID, error) {
+	return NewULIDAt(time.Now(), defaultEntropy)
+}
+
+// NewULIDAt returns a ULID of t. If entropy is a MonotonicReader, MonotonicRead is used,
+// and the timestamp may be later than t when the clock has moved backwards.
+// crypto/rand is used if entropy is nil.
+func NewULIDAt(t time.Time, entropy io.Reader) (ULID, error) {
+	var u ULID
+	ms := t.UnixMilli()
+	if ms < 0 || ms > ulidMaxTime {
+		return u, ErrULIDTime
+	}
+	if entropy == nil {
+		entropy = rand.Reader
+	}
+
+	var err error
+	if m, ok := entropy.(MonotonicReader); ok {
+		var usedMs uint64
+		usedMs, err = m.MonotonicRead(uint64(ms), u[6:])
+		ms = int64(usedMs)
+	} else {
+		_, err = io.ReadFull(entropy, u[6:])
+	}
+	if err != nil {
+		return ULID{}, err
+	}
+	u[0] = byte(ms >> 40)
+	u[1] = byte(ms >> 32)
+	u[2] = byte(ms >> 24)
+	u[3] = byte(ms >> 16)
+	u[4] = byte(ms >> 8)
+	u[5] = byte(ms)
+	return u, nil
+}
+
+// Time returns the timestamp of the ULID
+func (u ULID) Time() time.Time {
+	ms := int64(u[0])<<40 | int64(u[1])<<32 | int64(u[2])<<24 | int64(u[3])<<16 | int64(u[4])<<8 | int64(u[5])
+	return time.UnixMilli(ms)
+}
+
+// Compare returns an integer comparing two ULIDs lexicographically (the same order as their strings).
+// The result will be 0 if u == other, -1 if u < other, and +1 if u > other.
+func (u ULID) Compare(other ULID) int {
+	return bytes.Compare(u[:], other[:])
+}
+
+// String returns the 26 characters Crockford base32 of the ULID
+func (u ULID) String() string {
+	var buf [26]byte
+	u.encode(buf[:])
+	return string(buf[:])
+}
+
+// encode 128 bits => 26 * 5 bits, 最前面多出2個bit
+func (u ULID) encode(dst []byte) {
+	var hi, lo uint64 // 128 bits
+	for i := 0; i < 8; i++ {
+		hi = hi<<8 | uint64(u[i])
+		lo = lo<<8 | uint64(u[i+8])
+	}
+	for i := 25; i >= 0; i-- {
+		dst[i] = ulidEncoding[lo&0x1F]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+}
+
+// ParseULID parses the 26 characters Crockford base32 (case-insensitive)
+func ParseULID(s string) (ULID, error) {
+	var u ULID
+	if len(s) != 26 {
+		return u, fmt.Errorf("%w: the length of %q must be 26", ErrInvalidULID, s)
+	}
+	var hi, lo uint64
+	for i := 0; i < 26; i++ {
+		d := ulidDecoding[s[i]]
+		if d == 0xFF {
+			return u, fmt.Errorf("%w: invalid character %q in %q", ErrInvalidULID, s[i], s)
+		}
+		if i == 0 && d > 7 { // 第一個字元只有3個bit可以用
+			return u, fmt.Errorf("%w: %q overflows 128 bits", ErrInvalidULID, s)
+		}
+		hi = hi<<5 | lo>>59
+		lo = lo<<5 | uint64(d)
+	}
+	for i := 7; i >= 0; i-- {
+		u[i] = byte(hi)
+		u[i+8] = byte(lo)
+		hi >>= 8
+		lo >>= 8
+	}
+	return u, nil
+}
+
+// MustParseULID is like ParseULID but panics if the string cannot be parsed
+func MustParseULID(s string) ULID {
+	u, err := ParseULID(s)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
+
+// MarshalText implements the encoding.TextMarshaler
+func (u ULID) MarshalText() ([]byte, error) {
+	buf := make([]byte, 26)
+	u.encode(buf)
+	return buf, nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler
+func (u *ULID) UnmarshalText(b []byte) error {
+	v, err := ParseULID(string(b))
+	if err != nil {
+		return err
+	}
+	*u = v
+	return nil
+}
@@ -0,0 +1,156 @@
+package rand
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseULID(t *testing.T) {
+	u, err := ParseULID("01ARZ3NDEKTSV4RRFFQ69G5FAV")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(u[:], mustHex("01563e3ab5d3d6764c61efb99302bd5b")) {
+		t.Fatalf("%x", u)
+	}
+	if u.Time().UnixMilli() != 1469922850259 {
+		t.Fatal(u.Time())
+	}
+	if u.String() != "01ARZ3NDEKTSV4RRFFQ69G5FAV" {
+		t.Fatal(u)
+	}
+	if v, _ := ParseULID("01arz3ndektsv4rrffq69g5fav"); v != u {
+		t.Fatal("case-insensitive")
+	}
+
+	maxULID, err := ParseULID("7ZZZZZZZZZZZZZZZZZZZZZZZZZ")
+	if err != nil || maxULID != (ULID{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}) {
+		t.Fatal(maxULID, err)
+	}
+
+	for _, s := range []string{
+		"",
+		"01ARZ3NDEKTSV4RRFFQ69G5FA",
+		"01ARZ3NDEKTSV4RRFFQ69G5FAVV",
+		"01ARZ3NDEKTSV4RRFFQ69G5FAU", // U不在字母表內
+		"01ARZ3NDEKTSV4RRFFQ69G5FA-",
+		"80000000000000000000000000", // overflow
+	} {
+		if _, err = ParseULID(s); !errors.Is(err, ErrInvalidULID) {
+			t.Fatal(s, err)
+		}
+	}
+}
+
+func TestNewULID(t *testing.T) {
+	var list []string
+	var prev ULID
+	for i := 0; i < 1000; i++ {
+		u, err := NewULID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.Compare(prev) <= 0 {
+			t.Fatalf("%s <= %s", u, prev)
+		}
+		prev = u
+		list = append(list, u.String())
+	}
+	if !sort.StringsAreSorted(list) {
+		t.Fatal("the strings must be sorted too")
+	}
+	if time.Since(prev.Time()) > time.Minute {
+		t.Fatal(prev.Time())
+	}
+
+	at := time.UnixMilli(1469922850259)
+	u, err := NewULIDAt(at, bytes.NewReader(mustHex("d6764c61efb99302bd5b")))
+	if err != nil || u.String() != "01ARZ3NDEKTSV4RRFFQ69G5FAV" {
+		t.Fatal(u, err)
+	}
+	if _, err = NewULIDAt(time.UnixMilli(-1), defaultEntropy); !errors.Is(err, ErrULIDTime) {
+		t.Fatal(err)
+	}
+	if _, err = NewULIDAt(time.UnixMilli(1<<48), defaultEntropy); !errors.Is(err, ErrULIDTime) {
+		t.Fatal(err)
+	}
+}
+
+func TestMonotonicEntropy(t *testing.T) {
+	at := time.UnixMilli(1469922850259)
+	entropy := NewMonotonicEntropy(bytes.NewReader(append(bytes.Repeat([]byte{0xFF}, 9), 0xFD, 0x00)))
+	var list []ULID
+	for i := 0; i < 3; i++ { // FD, FE, FF
+		u, err := NewULIDAt(at, entropy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		list = append(list, u)
+	}
+	if list[0].Compare(list[1]) != -1 || list[1].Compare(list[2]) != -1 || list[2].Compare(list[2]) != 0 {
+		t.Fatal(list)
+	}
+	if !strings.HasSuffix(list[2].String(), "ZZZZZZZZZZZZZZZZ") {
+		t.Fatal(list[2])
+	}
+
+	// 用完了
+	if _, err := NewULIDAt(at, entropy); !errors.Is(err, ErrMonotonicOverflow) {
+		t.Fatal(err)
+	}
+	if _, err := NewULIDAt(at, entropy); !errors.Is(err, ErrMonotonicOverflow) {
+		t.Fatal(err)
+	}
+}
+
+func TestMonotonicEntropy_ClockBackward(t *testing.T) {
+	entropy := NewMonotonicEntropy(nil)
+	at := time.UnixMilli(1469922850259)
+	prev, err := NewULIDAt(at, entropy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 時間倒退: 使用上一個毫秒，仍然遞增
+	for _, back := range []time.Duration{time.Second, time.Millisecond, time.Hour} {
+		u, err := NewULIDAt(at.Add(-back), entropy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.Compare(prev) != 1 || !u.Time().Equal(at) {
+			t.Fatal(back, prev, u)
+		}
+		prev = u
+	}
+	// 時間前進之後使用新的時間
+	u, err := NewULIDAt(at.Add(time.Millisecond), entropy)
+	if err != nil || u.Compare(prev) != 1 || !u.Time().Equal(at.Add(time.Millisecond)) {
+		t.Fatal(u, err)
+	}
+}
+
+func TestNewULIDAt_NilEntropy(t *testing.T) {
+	at := time.UnixMilli(1469922850259)
+	a, err := NewULIDAt(at, nil)
+	if err != nil || !a.Time().Equal(at) {
+		t.Fatal(a, err)
+	}
+	if b, _ := NewULIDAt(at, nil); a == b {
+		t.Fatal("the entropy must be random")
+	}
+}
+
+func TestULID_Text(t *testing.T) {
+	u := MustParseULID("01ARZ3NDEKTSV4RRFFQ69G5FAV")
+	b, _ := u.MarshalText()
+	var v ULID
+	if err := v.UnmarshalText(b); err != nil || v != u {
+		t.Fatal(v, err)
+	}
+	if err := v.UnmarshalText([]byte("abc")); err == nil {
+		t.Fatal()
+	}
+}
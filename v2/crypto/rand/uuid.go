@@ -2,12 +2,226 @@ package rand
 
 import (
 	"crypto/rand"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"strings"
 )
 
+var ErrInvalidUUID = errors.New("invalid UUID")
+
+// UUID RFC 4122 / RFC 9562
+type UUID [16]byte
+
+// Nil is the UUID with all bits set to zero
+var Nil UUID
+
+// Version is the version of the UUID (the high 4 bits of the 7th byte)
+type Version uint8
+
+// Variant is the layout of the UUID (the high bits of the 9th byte)
+type Variant uint8
+
+const (
+	VariantNCS       Variant = iota // 0xx, reserved, NCS backward compatibility
+	VariantRFC4122                  // 10x, RFC 4122 / RFC 9562
+	VariantMicrosoft                // 110, reserved, Microsoft backward compatibility
+	VariantFuture                   // 111, reserved for future definition
+)
+
+func (v Variant) String() string {
+	switch v {
+	case VariantNCS:
+		return "NCS"
+	case VariantRFC4122:
+		return "RFC4122"
+	case VariantMicrosoft:
+		return "Microsoft"
+	default:
+		return "Future"
+	}
+}
+
+// NewUUID returns a random (version 4) UUID in the canonical form, e.g. 9b2ad3b4-6c5d-4e8f-a1b2-c3d4e5f6a7b8
 func NewUUID() string {
-	b := make([]byte, 16)
-	_, _ = rand.Read(b)
-	// %X 是一個nibble, 4bit, half-byte
-	return fmt.Sprintf("%X-%X-%X-%X-%X", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+	u, err := NewV4()
+	if err != nil {
+		panic(err) // crypto/rand壞掉的情況下，不應該繼續產生可以被猜到的UUID
+	}
+	return u.String()
+}
+
+// NewV4 returns a random UUID from crypto/rand
+func NewV4() (UUID, error) {
+	return NewV4FromReader(rand.Reader)
+}
+
+// NewV4FromReader is the same as NewV4 but the random bytes come from r
+func NewV4FromReader(r io.Reader) (UUID, error) {
+	var u UUID
+	if _, err := io.ReadFull(r, u[:]); err != nil {
+		return Nil, err
+	}
+	u.setVersion(4)
+	return u, nil
+}
+
+// setVersion 設定版本號以及RFC4122的variant
+func (u *UUID) setVersion(v Version) {
+	u[6] = u[6]&0x0F | byte(v)<<4
+	u[8] = u[8]&0x3F | 0x80 // 10xx xxxx
+}
+
+// Version returns the version of the UUID
+func (u UUID) Version() Version {
+	return Version(u[6] >> 4)
+}
+
+// Variant returns the variant of the UUID
+func (u UUID) Variant() Variant {
+	switch {
+	case u[8]&0x80 == 0:
+		return VariantNCS
+	case u[8]&0xC0 == 0x80:
+		return VariantRFC4122
+	case u[8]&0xE0 == 0xC0:
+		return VariantMicrosoft
+	default:
+		return VariantFuture
+	}
+}
+
+// IsNil reports whether the UUID is Nil
+func (u UUID) IsNil() bool {
+	return u == Nil
+}
+
+// String returns the canonical form: xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx (lowercase)
+func (u UUID) String() string {
+	var buf [36]byte
+	u.encode(buf[:])
+	return string(buf[:])
+}
+
+func (u UUID) encode(buf []byte) {
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+}
+
+// Parse accepts the following forms (case-insensitive):
+//
+//	6ba7b810-9dad-11d1-80b4-00c04fd430c8
+//	{6ba7b810-9dad-11d1-80b4-00c04fd430c8}
+//	urn:uuid:6ba7b810-9dad-11d1-80b4-00c04fd430c8
+//	6ba7b8109dad11d180b400c04fd430c8
+func Parse(s string) (UUID, error) {
+	var u UUID
+	src := s
+	switch len(s) {
+	case 36:
+	case 38:
+		if s[0] != '{' || s[37] != '}' {
+			return Nil, fmt.Errorf("%w: %q", ErrInvalidUUID, src)
+		}
+		s = s[1:37]
+	case 45:
+		if !strings.EqualFold(s[:9], "urn:uuid:") {
+			return Nil, fmt.Errorf("%w: %q", ErrInvalidUUID, src)
+		}
+		s = s[9:]
+	case 32:
+		if _, err := hex.Decode(u[:], []byte(s)); err != nil {
+			return Nil, fmt.Errorf("%w: %q", ErrInvalidUUID, src)
+		}
+		return u, nil
+	default:
+		return Nil, fmt.Errorf("%w: the length of %q is %d", ErrInvalidUUID, src, len(src))
+	}
+
+	if s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return Nil, fmt.Errorf("%w: %q", ErrInvalidUUID, src)
+	}
+	b := []byte(s[0:8] + s[9:13] + s[14:18] + s[19:23] + s[24:])
+	if _, err := hex.Decode(u[:], b); err != nil {
+		return Nil, fmt.Errorf("%w: %q", ErrInvalidUUID, src)
+	}
+	return u, nil
+}
+
+// MustParse is like Parse but panics if the string cannot be parsed
+func MustParse(s string) UUID {
+	u, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
+
+// MarshalText implements the encoding.TextMarshaler
+func (u UUID) MarshalText() ([]byte, error) {
+	buf := make([]byte, 36)
+	u.encode(buf)
+	return buf, nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler
+func (u *UUID) UnmarshalText(b []byte) error {
+	v, err := Parse(string(b))
+	if err != nil {
+		return err
+	}
+	*u = v
+	return nil
+}
+
+// MarshalJSON encodes the UUID as a JSON string
+func (u UUID) MarshalJSON() ([]byte, error) {
+	buf := make([]byte, 38)
+	buf[0], buf[37] = '"', '"'
+	u.encode(buf[1:37])
+	return buf, nil
+}
+
+// UnmarshalJSON accepts a JSON string in any form that Parse supports. null is ignored.
+func (u *UUID) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		return nil
+	}
+	if len(b) < 2 || b[0] != '"' || b[len(b)-1] != '"' {
+		return fmt.Errorf("%w: %s", ErrInvalidUUID, b)
+	}
+	return u.UnmarshalText(b[1 : len(b)-1])
+}
+
+// Value implements the driver.Valuer, the UUID is stored as the canonical string.
+func (u UUID) Value() (driver.Value, error) {
+	return u.String(), nil
+}
+
+// Scan implements the sql.Scanner. The src can be a string, 16 raw bytes or the text in []byte. NULL becomes Nil.
+func (u *UUID) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*u = Nil
+	case string:
+		return u.UnmarshalText([]byte(v))
+	case []byte:
+		if len(v) == 16 {
+			copy(u[:], v)
+			return nil
+		}
+		return u.UnmarshalText(v)
+	default:
+		return fmt.Errorf("%w: can't scan %T into UUID", ErrInvalidUUID, src)
+	}
+	return nil
 }
@@ -0,0 +1,203 @@
+package rand
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/binary"
+	"hash"
+	"io"
+	"sync"
+	"time"
+)
+
+// Well known namespaces for the name-based UUID (RFC 9562 Section 6.6)
+var (
+	NamespaceDNS  = MustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	NamespaceURL  = MustParse("6ba7b811-9dad-11d1-80b4-00c04fd430c8")
+	NamespaceOID  = MustParse("6ba7b812-9dad-11d1-80b4-00c04fd430c8")
+	NamespaceX500 = MustParse("6ba7b814-9dad-11d1-80b4-00c04fd430c8")
+)
+
+// gregorianOffset 1582-10-15 到 1970-01-01 之間有幾個100奈秒
+const gregorianOffset = 0x01B21DD213814000
+
+// NewV3 returns a name-based UUID using MD5. Use NewV5 unless you need the compatibility.
+func NewV3(namespace UUID, name string) UUID {
+	return newHashed(md5.New(), 3, namespace, name)
+}
+
+// NewV5 returns a name-based UUID using SHA-1, the same namespace and name always give the same UUID.
+func NewV5(namespace UUID, name string) UUID {
+	return newHashed(sha1.New(), 5, namespace, name)
+}
+
+func newHashed(h hash.Hash, v Version, namespace UUID, name string) UUID {
+	h.Write(namespace[:])
+	h.Write([]byte(name))
+	var u UUID
+	copy(u[:], h.Sum(nil))
+	u.setVersion(v)
+	return u
+}
+
+// Generator creates the time-based UUIDs (version 1, 6, 7). It's safe for concurrent use.
+type Generator struct {
+	mutex sync.Mutex
+	rand  io.Reader
+	now   func() time.Time
+
+	// v7
+	lastMs  int64
+	counter uint16 // rand_a的12碼，同一毫秒內遞增
+
+	// v1, v6
+	lastTicks uint64
+	clockSeq  uint16
+	node      [6]byte
+	nodeReady bool
+}
+
+// NewGenerator returns a Generator. r and now can be nil, crypto/rand.Reader and time.Now are used by default.
+func NewGenerator(r io.Reader, now func() time.Time) *Generator {
+	if r == nil {
+		r = rand.Reader
+	}
+	if now == nil {
+		now = time.Now
+	}
+	return &Generator{rand: r, now: now}
+}
+
+var defaultGenerator = NewGenerator(nil, nil)
+
+// NewV7 returns a time-ordered UUID from the default Generator, see Generator.NewV7
+func NewV7() (UUID, error) {
+	return defaultGenerator.NewV7()
+}
+
+// NewV6 returns a time-ordered UUID from the default Generator, see Generator.NewV6
+func NewV6() (UUID, error) {
+	return defaultGenerator.NewV6()
+}
+
+// NewV1 returns a time-based UUID from the default Generator, see Generator.NewV1
+func NewV1() (UUID, error) {
+	return defaultGenerator.NewV1()
+}
+
+// NewV7 returns a UUID with the unix timestamp in milliseconds, so it can be sorted by time.
+// Within the same millisecond the 12-bit rand_a is used as a counter (RFC 9562 Section 6.2, Method 1),
+// which starts at a random value. When it runs out, the timestamp is moved to the next millisecond.
+// The UUIDs from the same Generator are strictly increasing, even if the clock moves backwards.
+func (g *Generator) NewV7() (UUID, error) {
+	var u UUID
+	if _, err := io.ReadFull(g.rand, u[6:]); err != nil { // rand_a的部份會被counter蓋掉，但第一次需要當作種子
+		return Nil, err
+	}
+
+	g.mutex.Lock()
+	ms := g.now().UnixMilli()
+	if ms <= g.lastMs {
+		ms = g.lastMs
+		g.counter++
+		if g.counter > 0x0FFF {
+			ms++
+			g.counter = 0
+		}
+	} else {
+		g.counter = binary.BigEndian.Uint16(u[6:8]) & 0x0FFF
+	}
+	g.lastMs = ms
+	counter := g.counter
+	g.mutex.Unlock()
+
+	u[0] = byte(ms >> 40)
+	u[1] = byte(ms >> 32)
+	u[2] = byte(ms >> 24)
+	u[3] = byte(ms >> 16)
+	u[4] = byte(ms >> 8)
+	u[5] = byte(ms)
+	binary.BigEndian.PutUint16(u[6:8], counter)
+	u.setVersion(7)
+	return u, nil
+}
+
+// ticks 取得Gregorian時間(100奈秒)，並確保嚴格遞增
+func (g *Generator) ticks() (uint64, uint16, [6]byte, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if !g.nodeReady {
+		// clock sequence與node都用亂數 (RFC 9562 Section 6.10)，不洩漏MAC位址
+		var b [8]byte
+		if _, err := io.ReadFull(g.rand, b[:]); err != nil {
+			return 0, 0, [6]byte{}, err
+		}
+		g.clockSeq = binary.BigEndian.Uint16(b[:2]) & 0x3FFF
+		copy(g.node[:], b[2:])
+		g.node[0] |= 0x01 // multicast bit, 表示不是真正的MAC位址
+		g.nodeReady = true
+	}
+
+	now := g.now()
+	ticks := uint64(now.Unix())*10_000_000 + uint64(now.Nanosecond()/100) + gregorianOffset
+	if ticks <= g.lastTicks {
+		ticks = g.lastTicks + 1
+	}
+	g.lastTicks = ticks
+	return ticks, g.clockSeq, g.node, nil
+}
+
+// NewV1 returns a UUID with the Gregorian timestamp, the clock sequence and a random node.
+// Prefer NewV6 or NewV7 for new systems, the bytes of version 1 are not sorted by time.
+func (g *Generator) NewV1() (UUID, error) {
+	ticks, seq, node, err := g.ticks()
+	if err != nil {
+		return Nil, err
+	}
+	var u UUID
+	binary.BigEndian.PutUint32(u[0:4], uint32(ticks))
+	binary.BigEndian.PutUint16(u[4:6], uint16(ticks>>32))
+	binary.BigEndian.PutUint16(u[6:8], uint16(ticks>>48))
+	binary.BigEndian.PutUint16(u[8:10], seq)
+	copy(u[10:], node[:])
+	u.setVersion(1)
+	return u, nil
+}
+
+// NewV6 is the same as NewV1, but the timestamp is stored from the most significant bits, so it can be sorted by time.
+func (g *Generator) NewV6() (UUID, error) {
+	ticks, seq, node, err := g.ticks()
+	if err != nil {
+		return Nil, err
+	}
+	var u UUID
+	binary.BigEndian.PutUint64(u[0:8], ticks<<4)
+	u[6] = byte(ticks>>8) & 0x0F
+	u[7] = byte(ticks)
+	binary.BigEndian.PutUint16(u[8:10], seq)
+	copy(u[10:], node[:])
+	u.setVersion(6)
+	return u, nil
+}
+
+// Time returns the time of a version 1, 6 or 7 UUID. ok is false for the other versions.
+func (u UUID) Time() (t time.Time, ok bool) {
+	var ticks uint64
+	switch u.Version() {
+	case 7:
+		ms := int64(u[0])<<40 | int64(u[1])<<32 | int64(u[2])<<24 | int64(u[3])<<16 | int64(u[4])<<8 | int64(u[5])
+		return time.UnixMilli(ms), true
+	case 1:
+		ticks = uint64(binary.BigEndian.Uint32(u[0:4])) |
+			uint64(binary.BigEndian.Uint16(u[4:6]))<<32 |
+			uint64(binary.BigEndian.Uint16(u[6:8])&0x0FFF)<<48
+	case 6:
+		ticks = binary.BigEndian.Uint64(u[0:8])>>4&^0x0FFF | uint64(binary.BigEndian.Uint16(u[6:8])&0x0FFF)
+	default:
+		return time.Time{}, false
+	}
+	ticks -= gregorianOffset
+	return time.Unix(int64(ticks/10_000_000), int64(ticks%10_000_000)*100), true
+}
@@ -0,0 +1,137 @@
+package rand
+
+import (
+	"bytes"
+	"encoding/hex"
+	"sync"
+	"testing"
+	"time"
+)
+
+// RFC 9562 Appendix A: Tuesday, February 22, 2022 2:22:22.00 PM GMT-05:00
+var rfcTime = time.Date(2022, 2, 22, 19, 22, 22, 0, time.UTC)
+
+func mustHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func TestNewV3V5(t *testing.T) {
+	for _, d := range []struct {
+		actual   UUID
+		expected string
+	}{
+		{NewV3(NamespaceDNS, "www.example.com"), "5df41881-3aed-3515-88a7-2f4a814cf09e"}, // RFC 9562 A.2
+		{NewV5(NamespaceDNS, "www.example.com"), "2ed6657d-e927-568b-95e1-2665a8aea6a2"}, // RFC 9562 A.4
+	} {
+		if d.actual.String() != d.expected {
+			t.Errorf("%s != %s", d.actual, d.expected)
+		}
+	}
+	if NewV5(NamespaceOID, "a") == NewV5(NamespaceX500, "a") {
+		t.Fatal("namespace must matter")
+	}
+}
+
+func TestGenerator_RFCVectors(t *testing.T) {
+	now := func() time.Time { return rfcTime }
+
+	// A.1, A.5: clock seq 0x33C8, node 9F6BDECED846
+	g := NewGenerator(bytes.NewReader(mustHex("33C89F6BDECED846")), now)
+	u, err := g.NewV1()
+	if err != nil || u.String() != "c232ab00-9414-11ec-b3c8-9f6bdeced846" {
+		t.Fatal(u, err)
+	}
+	g = NewGenerator(bytes.NewReader(mustHex("33C89F6BDECED846")), now)
+	u, err = g.NewV6()
+	if err != nil || u.String() != "1ec9414c-232a-6b00-b3c8-9f6bdeced846" {
+		t.Fatal(u, err)
+	}
+	if tm, ok := u.Time(); !ok || !tm.Equal(rfcTime) {
+		t.Fatal(tm)
+	}
+
+	// A.6: rand_a 0xCC3, rand_b 0x18C4DC0C0C07398F
+	g = NewGenerator(bytes.NewReader(mustHex("0CC398C4DC0C0C07398F")), now)
+	u, err = g.NewV7()
+	if err != nil || u.String() != "017f22e2-79b0-7cc3-98c4-dc0c0c07398f" {
+		t.Fatal(u, err)
+	}
+	if tm, ok := u.Time(); !ok || !tm.Equal(rfcTime) {
+		t.Fatal(tm)
+	}
+
+	if _, ok := NewV5(NamespaceDNS, "a").Time(); ok {
+		t.Fatal("v5 has no time")
+	}
+}
+
+func TestGenerator_NewV7Monotonic(t *testing.T) {
+	// 時間固定、亂數全是0xFF => counter從0xFFF開始，馬上就會用完
+	g := NewGenerator(bytes.NewReader(bytes.Repeat([]byte{0xFF}, 10*10001)), func() time.Time { return rfcTime })
+	var prev UUID
+	for i := 0; i < 10000; i++ {
+		u, err := g.NewV7()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Compare(u[:], prev[:]) <= 0 {
+			t.Fatalf("%d: %s <= %s", i, u, prev)
+		}
+		if u.Version() != 7 || u.Variant() != VariantRFC4122 {
+			t.Fatal(u)
+		}
+		prev = u
+	}
+	if tm, _ := prev.Time(); tm.Sub(rfcTime) != 3*time.Millisecond { // 1 + 4096 + 4096 + ...
+		t.Fatal(tm)
+	}
+
+	// 時間倒退也要遞增
+	g.now = func() time.Time { return rfcTime.Add(-time.Hour) }
+	u, _ := g.NewV7()
+	if bytes.Compare(u[:], prev[:]) <= 0 {
+		t.Fatal(u)
+	}
+}
+
+func TestNewV1V6V7(t *testing.T) {
+	var (
+		mutex sync.Mutex
+		seen  = map[UUID]bool{}
+		wg    sync.WaitGroup
+	)
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				for _, f := range []func() (UUID, error){NewV1, NewV6, NewV7} {
+					u, err := f()
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					mutex.Lock()
+					if seen[u] {
+						t.Errorf("duplicate %s", u)
+					}
+					seen[u] = true
+					mutex.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	before := time.Now().Add(-time.Second)
+	for _, f := range []func() (UUID, error){NewV1, NewV6, NewV7} {
+		u, _ := f()
+		if tm, ok := u.Time(); !ok || tm.Before(before) || tm.After(time.Now().Add(time.Second)) {
+			t.Fatal(u, tm)
+		}
+	}
+}
@@ -1,8 +1,25 @@
 package rand
 
 import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"encoding"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
+	"testing"
+)
+
+var (
+	_ json.Marshaler           = UUID{}
+	_ json.Unmarshaler         = (*UUID)(nil)
+	_ encoding.TextMarshaler   = UUID{}
+	_ encoding.TextUnmarshaler = (*UUID)(nil)
+	_ driver.Valuer            = UUID{}
+	_ sql.Scanner              = (*UUID)(nil)
 )
 
 func ExampleNewUUID() {
@@ -11,3 +28,133 @@ func ExampleNewUUID() {
 	fmt.Println(len(uuid) == len("BE76F2EC-F918-7FE8-41D2-83CF5A321988"))
 	// Output: true
 }
+
+func ExampleParse() {
+	u, _ := Parse("{6BA7B810-9DAD-11D1-80B4-00C04FD430C8}")
+	fmt.Println(u, u.Version(), u.Variant())
+	// Output: 6ba7b810-9dad-11d1-80b4-00c04fd430c8 1 RFC4122
+}
+
+func TestNewUUID(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		s := NewUUID()
+		if strings.ToLower(s) != s {
+			t.Fatal("must be lowercase", s)
+		}
+		u, err := Parse(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.Version() != 4 || u.Variant() != VariantRFC4122 {
+			t.Fatal(s)
+		}
+	}
+
+	if _, err := NewV4FromReader(bytes.NewReader(make([]byte, 15))); err == nil {
+		t.Fatal("not enough random bytes")
+	}
+	u, _ := NewV4FromReader(bytes.NewReader(bytes.Repeat([]byte{0xFF}, 16)))
+	if u.String() != "ffffffff-ffff-4fff-bfff-ffffffffffff" {
+		t.Fatal(u)
+	}
+}
+
+func TestParse(t *testing.T) {
+	expected := UUID{0x6b, 0xa7, 0xb8, 0x10, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}
+	for _, s := range []string{
+		"6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+		"6BA7B810-9DAD-11D1-80B4-00C04FD430C8",
+		"{6ba7b810-9dad-11d1-80b4-00c04fd430c8}",
+		"urn:uuid:6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+		"URN:UUID:6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+		"6ba7b8109dad11d180b400c04fd430c8",
+	} {
+		u, err := Parse(s)
+		if err != nil || u != expected {
+			t.Fatal(s, u, err)
+		}
+	}
+
+	for _, s := range []string{
+		"",
+		"6ba7b810-9dad-11d1-80b4-00c04fd430c",
+		"6ba7b810-9dad-11d1-80b4-00c04fd430cg",
+		"6ba7b810x9dad-11d1-80b4-00c04fd430c8",
+		"(6ba7b810-9dad-11d1-80b4-00c04fd430c8)",
+		"urn:uid:-6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+		"6ba7b8109dad11d180b400c04fd430cz",
+	} {
+		if _, err := Parse(s); !errors.Is(err, ErrInvalidUUID) {
+			t.Fatal(s, err)
+		}
+	}
+}
+
+func TestUUID_Variant(t *testing.T) {
+	for _, d := range []struct {
+		b        byte
+		expected Variant
+	}{
+		{0x00, VariantNCS},
+		{0x7F, VariantNCS},
+		{0x80, VariantRFC4122},
+		{0xBF, VariantRFC4122},
+		{0xC0, VariantMicrosoft},
+		{0xDF, VariantMicrosoft},
+		{0xE0, VariantFuture},
+		{0xFF, VariantFuture},
+	} {
+		var u UUID
+		u[8] = d.b
+		if v := u.Variant(); v != d.expected {
+			t.Fatalf("%X: %s", d.b, v)
+		}
+	}
+	if !Nil.IsNil() || Nil.String() != "00000000-0000-0000-0000-000000000000" {
+		t.Fatal()
+	}
+}
+
+func TestUUID_JSON(t *testing.T) {
+	type Data struct {
+		ID   UUID            `json:"id"`
+		Refs map[UUID]string `json:"refs"`
+	}
+	u := MustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	b, err := json.Marshal(Data{u, map[UUID]string{u: "a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"id":"6ba7b810-9dad-11d1-80b4-00c04fd430c8","refs":{"6ba7b810-9dad-11d1-80b4-00c04fd430c8":"a"}}` {
+		t.Fatal(string(b))
+	}
+	var d Data
+	if err = json.Unmarshal(b, &d); err != nil || d.ID != u || d.Refs[u] != "a" {
+		t.Fatal(d, err)
+	}
+	if err = json.Unmarshal([]byte(`{"id":"abc"}`), &d); err == nil {
+		t.Fatal("invalid")
+	}
+	if err = json.Unmarshal([]byte(`{"id":123}`), &d); err == nil {
+		t.Fatal("number")
+	}
+}
+
+func TestUUID_SQL(t *testing.T) {
+	u := MustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	if v, _ := u.Value(); v != "6ba7b810-9dad-11d1-80b4-00c04fd430c8" {
+		t.Fatal(v)
+	}
+	for _, src := range []any{"6ba7b810-9dad-11d1-80b4-00c04fd430c8", []byte("6BA7B810-9DAD-11D1-80B4-00C04FD430C8"), u[:]} {
+		var got UUID
+		if err := got.Scan(src); err != nil || got != u {
+			t.Fatal(src, err)
+		}
+	}
+	if err := u.Scan(nil); err != nil || u != Nil {
+		t.Fatal(err)
+	}
+	if err := u.Scan(123); err == nil {
+		t.Fatal("int")
+	}
+}
@@ -0,0 +1,51 @@
+package snowflake
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+var (
+	ErrEpochInFuture   = errors.New("the epoch is in the future")
+	ErrLayoutExhausted = errors.New("the timestamp of the layout is exhausted")
+)
+
+// Capacity is the result of Layout.Analyze
+type Capacity struct {
+	MaxNodes       int64     // 最多可以有幾個機器
+	IDsPerTick     int64     // 每個機器在一個時間單位內可以生成幾個ID
+	IDsPerSecond   float64   // 每個機器每秒可以生成幾個ID
+	EndOfLife      time.Time // 從這個時間開始，時間戳記會溢位到符號位
+	YearsRemaining float64   // 距離EndOfLife還有幾年，已經用完會是負數
+}
+
+// EndOfLife returns the time when the timestamp overflows into the sign bit
+func (l Layout) EndOfLife() time.Time {
+	return l.timeAt(new(big.Int).Lsh(big.NewInt(1), uint(l.TimeBits()))) // MaxTick + 1，用big.Int是因為TimeBits為63的時候會溢位
+}
+
+// Analyze reports the capacity and the lifetime of the layout
+func (l Layout) Analyze(now time.Time) Capacity {
+	end := l.EndOfLife()
+	idsPerTick := l.MaskStep() + 1
+	return Capacity{
+		MaxNodes:       l.MaxNode() + 1,
+		IDsPerTick:     idsPerTick,
+		IDsPerSecond:   float64(idsPerTick) * float64(time.Second) / float64(l.TimeUnit()),
+		EndOfLife:      end,
+		YearsRemaining: float64(end.Unix()-now.Unix()) / (365.25 * 24 * 60 * 60), // time.Duration最多只能表示約292年，所以用秒來算
+	}
+}
+
+// checkLifetime 確認在now的時間點，這個layout是否還能使用
+func (l Layout) checkLifetime(now time.Time) error {
+	if l.Epoch.After(now) {
+		return fmt.Errorf("%w: %s", ErrEpochInFuture, l.Epoch)
+	}
+	if end := l.EndOfLife(); !now.Before(end) {
+		return fmt.Errorf("%w: it ended at %s", ErrLayoutExhausted, end)
+	}
+	return nil
+}
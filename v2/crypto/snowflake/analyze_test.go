@@ -0,0 +1,48 @@
+package snowflake_test
+
+import (
+	"errors"
+	"github.com/CarsonSlovoka/go-pkg/v2/crypto/snowflake"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestLayout_Analyze(t *testing.T) {
+	epoch := time.Date(2010, 11, 4, 1, 42, 54, 657000000, time.UTC) // Twitter
+	layout := snowflake.NewLayout(epoch, 10, 12)
+	c := layout.Analyze(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	if c.MaxNodes != 1024 || c.IDsPerTick != 4096 || c.IDsPerSecond != 4096000 {
+		t.Fatalf("%+v", c)
+	}
+	if expected := epoch.Add((1 << 41) * time.Millisecond); !c.EndOfLife.Equal(expected) { // 2080-07-10
+		t.Fatal(c.EndOfLife)
+	}
+	if math.Abs(c.YearsRemaining-56.5) > 0.1 {
+		t.Fatal(c.YearsRemaining)
+	}
+
+	// Sonyflake: 39碼, 10ms, 16碼機器碼, 8碼流水號
+	sony := snowflake.Layout{Epoch: epoch, NodeBits: 16, StepBits: 8, Unit: 10 * time.Millisecond}
+	c = sony.Analyze(epoch)
+	if c.MaxNodes != 65536 || c.IDsPerTick != 256 || c.IDsPerSecond != 25600 || math.Abs(c.YearsRemaining-174.2) > 0.1 {
+		t.Fatalf("%+v", c)
+	}
+
+	// 已經用完的會是負數
+	if c = snowflake.NewLayout(epoch, 26, 10).Analyze(time.Now()); c.YearsRemaining >= 0 {
+		t.Fatalf("%+v", c)
+	}
+}
+
+func TestNewNodeLifetime(t *testing.T) {
+	if _, err := snowflake.NewNode(0, time.Now().Add(time.Hour), 10, 12); !errors.Is(err, snowflake.ErrEpochInFuture) {
+		t.Fatal(err)
+	}
+	if _, err := snowflake.NewNode(0, time.Now().AddDate(-70, 0, 0), 10, 12); !errors.Is(err, snowflake.ErrLayoutExhausted) {
+		t.Fatal(err)
+	}
+	if _, err := snowflake.NewNode(0, time.Now().AddDate(-68, 0, 0), 10, 12); err != nil {
+		t.Fatal(err)
+	}
+}
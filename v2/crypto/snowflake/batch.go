@@ -0,0 +1,98 @@
+package snowflake
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// GenerateN creates count IDs in one critical section.
+// Once a time unit is taken, all the remaining steps of it are used without asking the clock again.
+// Like Generate, it panics if the clock moved backwards and the BackwardPolicy can't handle it.
+func (n *Node) GenerateN(count int) []ID {
+	if count <= 0 {
+		return nil
+	}
+	ids := make([]ID, 0, count)
+
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	for len(ids) < count {
+		id, err := n.next()
+		if err != nil {
+			panic(err)
+		}
+		ids = append(ids, id)
+
+		// 同一個時間單位剩下的流水號一次用完
+		base := id &^ ID(n.maskStep)
+		for n.step < n.maskStep && len(ids) < count {
+			n.step++
+			ids = append(ids, base|ID(n.step))
+		}
+	}
+	return ids
+}
+
+// LockFreeNode is a generator without mutex.
+// The time and the step are packed into one word ([time][step]) which is updated by compare-and-swap.
+// When the steps of a time unit run out, the step carries into the time, then Generate waits until the clock reaches it.
+// A clock that moves backwards is handled in the same way, as if the policy is BackwardWait without limit.
+type LockFreeNode struct {
+	state atomic.Int64 // [time][step]
+
+	layout    Layout
+	unit      time.Duration
+	clock     Clock
+	nodeBits  int64 // 已經移好位的機器碼
+	numStep   uint8
+	maskStep  int64
+	shiftTime uint8
+}
+
+// NewLockFreeNode returns a LockFreeNode. The options are the same as NewNode's, the clock must be safe for concurrent use.
+func NewLockFreeNode(psw int64, layout Layout, opts ...Option) (*LockFreeNode, error) {
+	n, err := NewNodeWithLayout(psw, layout, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &LockFreeNode{
+		layout:    n.layout,
+		unit:      n.unit,
+		clock:     n.clock,
+		nodeBits:  n.node << n.shiftNode,
+		numStep:   n.layout.StepBits,
+		maskStep:  n.maskStep,
+		shiftTime: n.shiftTime,
+	}, nil
+}
+
+// Layout returns the layout that the node was built from
+func (n *LockFreeNode) Layout() Layout {
+	return n.layout
+}
+
+// Generate creates the next ID, it's safe for concurrent use.
+func (n *LockFreeNode) Generate() ID {
+	for {
+		old := n.state.Load()
+		now := int64(n.clock.Now().Sub(n.layout.Epoch) / n.unit)
+
+		var next int64
+		if now > old>>n.numStep {
+			next = now << n.numStep
+		} else {
+			next = old + 1 // 流水號滿了會自動進位到時間
+		}
+		if !n.state.CompareAndSwap(old, next) {
+			continue
+		}
+
+		t := next >> n.numStep
+		if t > now { // 借用了未來的時間，等時鐘追上再回傳，避免跑得比時鐘還快
+			if d := n.layout.Epoch.Add(time.Duration(t) * n.unit).Sub(n.clock.Now()); d > 0 {
+				n.clock.Sleep(d)
+			}
+		}
+		return ID(t<<n.shiftTime | n.nodeBits | next&n.maskStep)
+	}
+}
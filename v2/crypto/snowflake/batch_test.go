@@ -0,0 +1,115 @@
+package snowflake_test
+
+import (
+	"github.com/CarsonSlovoka/go-pkg/v2/crypto/snowflake"
+	"sync"
+	"testing"
+)
+
+func TestNode_GenerateN(t *testing.T) {
+	n, _ := snowflake.NewNode(5, BaseT, 10, 4)
+	_ = n.Generate()
+	ids := n.GenerateN(1000)
+	if len(ids) != 1000 {
+		t.Fatal(len(ids))
+	}
+	for i := 1; i < len(ids); i++ {
+		if ids[i] <= ids[i-1] {
+			t.Fatalf("%d: %d <= %d", i, ids[i], ids[i-1])
+		}
+		if ids[i].Node(n.MaskNode(), n.ShiftNode()) != 5 {
+			t.Fatal()
+		}
+	}
+	if id := n.Generate(); id <= ids[len(ids)-1] {
+		t.Fatal(id)
+	}
+	if n.GenerateN(0) != nil {
+		t.Fatal()
+	}
+}
+
+func TestLockFreeNode(t *testing.T) {
+	layout := snowflake.NewLayout(BaseT, 10, 6)
+	n, err := snowflake.NewLockFreeNode(7, layout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = snowflake.NewLockFreeNode(1024, layout); err == nil {
+		t.Fatal("node out of range")
+	}
+
+	const numWorker, numID = 8, 5000
+	var wg sync.WaitGroup
+	results := make([][]snowflake.ID, numWorker)
+	for w := 0; w < numWorker; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			ids := make([]snowflake.ID, numID)
+			for i := range ids {
+				ids[i] = n.Generate()
+			}
+			results[w] = ids
+		}(w)
+	}
+	wg.Wait()
+
+	seen := make(map[snowflake.ID]struct{}, numWorker*numID)
+	for _, ids := range results {
+		for i, id := range ids {
+			if i > 0 && id <= ids[i-1] {
+				t.Fatalf("not increasing: %d <= %d", id, ids[i-1])
+			}
+			if _, exists := seen[id]; exists {
+				t.Fatalf("duplicate: %d", id)
+			}
+			seen[id] = struct{}{}
+			if parts := layout.Decompose(id); parts.Node != 7 {
+				t.Fatal(parts)
+			}
+		}
+	}
+}
+
+func BenchmarkNode_Generate(b *testing.B) {
+	n, _ := snowflake.NewNode(1, BaseT, 10, 12)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = n.Generate()
+	}
+}
+
+func BenchmarkNode_GenerateN(b *testing.B) {
+	n, _ := snowflake.NewNode(1, BaseT, 10, 12)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i += 1000 {
+		_ = n.GenerateN(1000)
+	}
+}
+
+func BenchmarkLockFreeNode_Generate(b *testing.B) {
+	n, _ := snowflake.NewLockFreeNode(1, snowflake.NewLayout(BaseT, 10, 12))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = n.Generate()
+	}
+}
+
+func BenchmarkNode_GenerateParallel(b *testing.B) {
+	n, _ := snowflake.NewNode(1, BaseT, 10, 12)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_ = n.Generate()
+		}
+	})
+}
+
+func BenchmarkLockFreeNode_GenerateParallel(b *testing.B) {
+	n, _ := snowflake.NewLockFreeNode(1, snowflake.NewLayout(BaseT, 10, 12))
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_ = n.Generate()
+		}
+	})
+}
@@ -0,0 +1,162 @@
+package snowflake
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+var (
+	ErrInvalidID  = errors.New("invalid snowflake ID")
+	ErrIDOverflow = errors.New("snowflake ID overflows int64")
+)
+
+const (
+	alphabetBase32 = "0123456789ABCDEFGHJKMNPQRSTVWXYZ" // Crockford: 去掉了 I, L, O, U
+	alphabetBase36 = "0123456789abcdefghijklmnopqrstuvwxyz"
+	alphabetBase58 = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz" // Bitcoin: 去掉了 0, O, I, l
+	alphabetBase62 = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+)
+
+var (
+	decodeBase32 = newDecodeMap(alphabetBase32)
+	decodeBase36 = newDecodeMap(alphabetBase36)
+	decodeBase58 = newDecodeMap(alphabetBase58)
+	decodeBase62 = newDecodeMap(alphabetBase62)
+)
+
+func init() {
+	// Crockford 解碼時不分大小寫，並且 I, L 視為 1; O 視為 0
+	for i := 0; i < len(alphabetBase32); i++ {
+		c := alphabetBase32[i]
+		if 'A' <= c && c <= 'Z' {
+			decodeBase32[c+'a'-'A'] = decodeBase32[c]
+		}
+	}
+	for _, c := range "iIlL" {
+		decodeBase32[c] = 1
+	}
+	for _, c := range "oO" {
+		decodeBase32[c] = 0
+	}
+
+	// base36 不分大小寫
+	for c := 'A'; c <= 'Z'; c++ {
+		decodeBase36[c] = decodeBase36[c+'a'-'A']
+	}
+}
+
+// newDecodeMap 記錄每一個字元所對應的數值，0xFF表示不合法的字元
+func newDecodeMap(alphabet string) *[256]byte {
+	var m [256]byte
+	for i := range m {
+		m[i] = 0xFF
+	}
+	for i := 0; i < len(alphabet); i++ {
+		m[alphabet[i]] = byte(i)
+	}
+	return &m
+}
+
+// encode 把id以alphabet的進位制表示，負數(不合法的snowflake ID)會以uint64來看待
+func encode(id ID, alphabet string) string {
+	base := uint64(len(alphabet))
+	v := uint64(id)
+	if v < base {
+		return string(alphabet[v])
+	}
+
+	var buf [64]byte
+	i := len(buf)
+	for v > 0 {
+		i--
+		buf[i] = alphabet[v%base]
+		v /= base
+	}
+	return string(buf[i:])
+}
+
+func decode(s string, base uint64, decodeMap *[256]byte) (ID, error) {
+	if s == "" {
+		return 0, fmt.Errorf("%w: empty string", ErrInvalidID)
+	}
+	var v uint64
+	for i := 0; i < len(s); i++ {
+		d := decodeMap[s[i]]
+		if d == 0xFF {
+			return 0, fmt.Errorf("%w: invalid character %q in %q", ErrInvalidID, s[i], s)
+		}
+		if v > (math.MaxInt64-uint64(d))/base { // v*base+d > MaxInt64
+			return 0, fmt.Errorf("%w: %q", ErrIDOverflow, s)
+		}
+		v = v*base + uint64(d)
+	}
+	return ID(v), nil
+}
+
+// Base32 returns a Crockford base32 string of the snowflake ID
+func (id *ID) Base32() string {
+	return encode(*id, alphabetBase32)
+}
+
+// Base36 returns a base36 string (0-9a-z) of the snowflake ID
+func (id *ID) Base36() string {
+	return encode(*id, alphabetBase36)
+}
+
+// Base58 returns a base58 string (Bitcoin alphabet) of the snowflake ID
+func (id *ID) Base58() string {
+	return encode(*id, alphabetBase58)
+}
+
+// Base62 returns a base62 string (0-9A-Za-z) of the snowflake ID
+func (id *ID) Base62() string {
+	return encode(*id, alphabetBase62)
+}
+
+// Base64 returns the URL-safe base64 (without padding) of the big-endian bytes of the snowflake ID.
+// The length is always 11.
+func (id *ID) Base64() string {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(*id))
+	return base64.RawURLEncoding.EncodeToString(b[:])
+}
+
+// ParseBase32 converts a Crockford base32 string into a snowflake ID.
+// As the Crockford spec says, it is case-insensitive and I, L are read as 1, O is read as 0.
+func ParseBase32(id string) (ID, error) {
+	return decode(id, 32, decodeBase32)
+}
+
+// ParseBase36 converts a base36 string (case-insensitive) into a snowflake ID
+func ParseBase36(id string) (ID, error) {
+	return decode(id, 36, decodeBase36)
+}
+
+// ParseBase58 converts a base58 string into a snowflake ID
+func ParseBase58(id string) (ID, error) {
+	return decode(id, 58, decodeBase58)
+}
+
+// ParseBase62 converts a base62 string into a snowflake ID
+func ParseBase62(id string) (ID, error) {
+	return decode(id, 62, decodeBase62)
+}
+
+// ParseBase64 converts a string created by ID.Base64 into a snowflake ID
+func ParseBase64(id string) (ID, error) {
+	if len(id) != 11 {
+		return 0, fmt.Errorf("%w: the length of %q must be 11", ErrInvalidID, id)
+	}
+	b, err := base64.RawURLEncoding.Strict().DecodeString(id)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidID, err)
+	}
+	v := binary.BigEndian.Uint64(b)
+	if v > math.MaxInt64 {
+		return 0, fmt.Errorf("%w: %q", ErrIDOverflow, id)
+	}
+	return ID(v), nil
+}
@@ -0,0 +1,96 @@
+package snowflake_test
+
+import (
+	"errors"
+	"github.com/CarsonSlovoka/go-pkg/v2/crypto/snowflake"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestEncoding(t *testing.T) {
+	type codec struct {
+		name   string
+		encode func(id *snowflake.ID) string
+		parse  func(string) (snowflake.ID, error)
+	}
+	codecs := []codec{
+		{"base32", (*snowflake.ID).Base32, snowflake.ParseBase32},
+		{"base36", (*snowflake.ID).Base36, snowflake.ParseBase36},
+		{"base58", (*snowflake.ID).Base58, snowflake.ParseBase58},
+		{"base62", (*snowflake.ID).Base62, snowflake.ParseBase62},
+		{"base64", (*snowflake.ID).Base64, snowflake.ParseBase64},
+	}
+
+	for _, id := range []snowflake.ID{0, 1, 31, 32, 57, 58, 1 << 40, gN.Generate(), math.MaxInt64} {
+		for _, c := range codecs {
+			s := c.encode(&id)
+			got, err := c.parse(s)
+			if err != nil || got != id {
+				t.Fatalf("%s %d %q: %d, %v", c.name, id, s, got, err)
+			}
+		}
+	}
+}
+
+func TestEncodingValue(t *testing.T) {
+	id := snowflake.ID(1234567890123456789)
+	for _, d := range []struct {
+		actual   string
+		expected string
+	}{
+		{id.Base32(), "128GGYHYYK08N"},
+		{id.Base36(), "9do1sj396nf9"},
+		{id.Base58(), "3sDK21t5nHJ"},
+		{id.Base62(), "1TCKi1nFuNh"},
+		{id.Base64(), "ESIQ9H3pgRU"},
+	} {
+		if d.actual != d.expected {
+			t.Errorf("%q != %q", d.actual, d.expected)
+		}
+	}
+}
+
+func TestParseStrict(t *testing.T) {
+	// Crockford
+	for _, s := range []string{"128ggyhyyk08n", "i28GGYHYYK08N", "L28GGYHYYK08N", "128GGYHYYKO8N"} {
+		if v, err := snowflake.ParseBase32(s); err != nil || v != 1234567890123456789 {
+			t.Fatal(s, v, err)
+		}
+	}
+	if v, err := snowflake.ParseBase32("O1"); err != nil || v != 1 {
+		t.Fatal(v, err)
+	}
+	if v, err := snowflake.ParseBase36("9DO1SJ396NF9"); err != nil || v != 1234567890123456789 {
+		t.Fatal(v, err)
+	}
+
+	for _, d := range []struct {
+		parse func(string) (snowflake.ID, error)
+		s     string
+		err   error
+	}{
+		{snowflake.ParseBase32, "", snowflake.ErrInvalidID},
+		{snowflake.ParseBase32, "U", snowflake.ErrInvalidID},
+		{snowflake.ParseBase32, "12-34", snowflake.ErrInvalidID},
+		{snowflake.ParseBase32, "7ZZZZZZZZZZZZ", nil}, // MaxInt64
+		{snowflake.ParseBase32, "8000000000000", snowflake.ErrIDOverflow},
+		{snowflake.ParseBase36, "-1", snowflake.ErrInvalidID},
+		{snowflake.ParseBase36, "1y2p0ij32e8e7", nil}, // MaxInt64
+		{snowflake.ParseBase36, "1y2p0ij32e8e8", snowflake.ErrIDOverflow},
+		{snowflake.ParseBase58, "0", snowflake.ErrInvalidID},
+		{snowflake.ParseBase58, "l", snowflake.ErrInvalidID},
+		{snowflake.ParseBase58, strings.Repeat("z", 20), snowflake.ErrIDOverflow},
+		{snowflake.ParseBase62, "abc!", snowflake.ErrInvalidID},
+		{snowflake.ParseBase62, "AzL8n0Y58m8", snowflake.ErrIDOverflow}, // MaxInt64 + 1
+		{snowflake.ParseBase64, "ESIQ9H3pgRU=", snowflake.ErrInvalidID},
+		{snowflake.ParseBase64, "ESIQ9H3pgR+", snowflake.ErrInvalidID},
+		{snowflake.ParseBase64, "ESIQ9H3pgRV", snowflake.ErrInvalidID}, // 多出來的bit不是0
+		{snowflake.ParseBase64, "__________w", snowflake.ErrIDOverflow},
+	} {
+		_, err := d.parse(d.s)
+		if d.err == nil && err != nil || d.err != nil && !errors.Is(err, d.err) {
+			t.Errorf("%q: %v", d.s, err)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package snowflake_test
 
 import (
+	"fmt"
 	. "github.com/CarsonSlovoka/go-pkg/v2/crypto/snowflake"
 	"log"
 )
@@ -41,3 +42,10 @@ func ExampleID_String() {
 	log.Println(id.Step(1024))
 	// Output:
 }
+
+func ExampleLayout_Decompose() {
+	layout := NewLayout(BaseT, 10, 12)
+	parts := layout.Decompose(ID(5<<22 | 3<<12 | 7))
+	fmt.Println(parts.Time.Sub(BaseT), parts.Node, parts.Step)
+	// Output: 5ms 3 7
+}
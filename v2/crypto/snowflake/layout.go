@@ -0,0 +1,91 @@
+package snowflake
+
+import (
+	"errors"
+	"strconv"
+	"time"
+)
+
+// A Layout describes how an ID is composed: [time][node][step]
+// 只要知道Layout，就可以在不需要Node的情況下解析ID
+type Layout struct {
+	Epoch    time.Time // 基準日
+	NodeBits uint8     // 機器碼有幾碼
+	StepBits uint8     // 流水號有幾碼
+
+	// Unit is the tick of the timestamp, e.g. time.Millisecond, 10 * time.Millisecond (Sonyflake), time.Second.
+	// 0 means time.Millisecond. 單位越大，能用的年限越長，但每個單位能生成的ID數量不變
+	Unit time.Duration
+}
+
+// Parts is the decomposed result of an ID
+type Parts struct {
+	Time time.Time
+	Node int64
+	Step int64
+}
+
+// NewLayout returns a Layout. Use Layout.Validate to check it.
+func NewLayout(epoch time.Time, numNodeBits, numStepBits uint8) Layout {
+	return Layout{Epoch: epoch, NodeBits: numNodeBits, StepBits: numStepBits}
+}
+
+// Validate reports an error if the bit widths do not fit in 63 bits (the sign bit is not used).
+// 至少要保留1碼給時間戳記
+func (l Layout) Validate() error {
+	if l.Unit < 0 {
+		return errors.New("unit must not be negative")
+	}
+	if int(l.NodeBits)+int(l.StepBits) >= 63 {
+		return errors.New("numNodeBits + numStepBits must be less than 63, got " +
+			strconv.Itoa(int(l.NodeBits)+int(l.StepBits)))
+	}
+	return nil
+}
+
+// TimeUnit returns the Unit, time.Millisecond if it is not set
+func (l Layout) TimeUnit() time.Duration {
+	if l.Unit == 0 {
+		return time.Millisecond
+	}
+	return l.Unit
+}
+
+// TimeBits returns how many bits the timestamp can use
+func (l Layout) TimeBits() uint8 {
+	return 63 - l.NodeBits - l.StepBits
+}
+
+// ShiftTime 時間戳記的位移碼位
+func (l Layout) ShiftTime() uint8 {
+	return l.NodeBits + l.StepBits
+}
+
+// ShiftNode 機器碼的位移碼位
+func (l Layout) ShiftNode() uint8 {
+	return l.StepBits
+}
+
+// MaxNode 機器碼的最大可生成數值
+func (l Layout) MaxNode() int64 {
+	return -1 ^ (-1 << l.NodeBits)
+}
+
+// MaskNode 機器碼的遮罩
+func (l Layout) MaskNode() int64 {
+	return l.MaxNode() << l.StepBits
+}
+
+// MaskStep 流水號遮罩
+func (l Layout) MaskStep() int64 {
+	return -1 ^ (-1 << l.StepBits)
+}
+
+// Decompose splits the id into its time, node and step
+func (l Layout) Decompose(id ID) Parts {
+	return Parts{
+		Time: l.TimeAt(int64(id) >> l.ShiftTime()),
+		Node: id.Node(l.MaskNode(), l.ShiftNode()),
+		Step: id.Step(l.MaskStep()),
+	}
+}
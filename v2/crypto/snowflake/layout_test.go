@@ -0,0 +1,74 @@
+package snowflake_test
+
+import (
+	"github.com/CarsonSlovoka/go-pkg/v2/crypto/snowflake"
+	"testing"
+	"time"
+)
+
+func TestLayout_Validate(t *testing.T) {
+	for _, d := range []struct {
+		numNode   uint8
+		numStep   uint8
+		isErr     bool
+		isNodeErr bool // NewNode是否會失敗 (雖然合法，但是時間戳記可能已經用完)
+	}{
+		{10, 12, false, false},
+		{0, 0, false, false},
+		{31, 31, false, true},
+		{32, 31, true, true},
+		{63, 0, true, true},
+		{200, 200, true, true}, // 相加會超過uint8
+	} {
+		err := snowflake.NewLayout(BaseT, d.numNode, d.numStep).Validate()
+		if (err != nil) != d.isErr {
+			t.Fatalf("%d %d: %v", d.numNode, d.numStep, err)
+		}
+		if _, err = snowflake.NewNode(0, BaseT, d.numNode, d.numStep); (err != nil) != d.isNodeErr {
+			t.Fatalf("NewNode %d %d: %v", d.numNode, d.numStep, err)
+		}
+	}
+}
+
+func TestLayout_Decompose(t *testing.T) {
+	layout := snowflake.NewLayout(BaseT, 10, 12)
+	n, err := snowflake.NewNodeWithLayout(123, layout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Layout() != layout {
+		t.Fatal()
+	}
+
+	id := n.Generate()
+	// 解析的一方不需要用到Node
+	parts := snowflake.NewLayout(BaseT, 10, 12).Decompose(id)
+	if parts.Node != 123 {
+		t.Fatal(parts.Node)
+	}
+	if parts.Step != id.Step(n.MaskStep()) {
+		t.Fatal(parts.Step)
+	}
+	if !parts.Time.Equal(id.Time(n.BaseTime(), n.ShiftTime())) {
+		t.Fatal(parts.Time)
+	}
+
+	// 手動組合: time=5ms, node=3, step=7
+	id = snowflake.ID(5<<22 | 3<<12 | 7)
+	parts = layout.Decompose(id)
+	if parts.Node != 3 || parts.Step != 7 || parts.Time.Sub(BaseT) != 5*time.Millisecond {
+		t.Fatalf("%+v", parts)
+	}
+
+	// 以秒為單位，超過time.Duration能表示的292年也不能溢位
+	layout = snowflake.Layout{Epoch: BaseT, NodeBits: 10, StepBits: 12, Unit: time.Second}
+	tick := int64(300 * 365 * 24 * 60 * 60)
+	id = snowflake.ID(tick<<22 | 3<<12 | 7)
+	parts = layout.Decompose(id)
+	if !parts.Time.Equal(BaseT.AddDate(0, 0, 300*365)) {
+		t.Fatalf("%+v", parts)
+	}
+	if !id.Time(BaseT, layout.ShiftTime(), time.Second).Equal(parts.Time) {
+		t.Fatal(id.Time(BaseT, layout.ShiftTime(), time.Second))
+	}
+}
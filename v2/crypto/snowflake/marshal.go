@@ -0,0 +1,69 @@
+package snowflake
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+)
+
+// MarshalJSON encodes the ID as a JSON string, JavaScript can't hold a 64-bit integer without losing precision.
+func (id ID) MarshalJSON() ([]byte, error) {
+	buf := make([]byte, 0, 21)
+	buf = append(buf, '"')
+	buf = strconv.AppendInt(buf, int64(id), 10)
+	return append(buf, '"'), nil
+}
+
+// UnmarshalJSON accepts both a string "123" and a number 123. null is ignored.
+func (id *ID) UnmarshalJSON(b []byte) error {
+	s := string(b)
+	if s == "null" {
+		return nil
+	}
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		s = s[1 : len(s)-1]
+	}
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidID, b)
+	}
+	*id = ID(i)
+	return nil
+}
+
+// MarshalText makes the ID usable as a JSON map key, or in any other text encoding.
+func (id ID) MarshalText() ([]byte, error) {
+	return strconv.AppendInt(nil, int64(id), 10), nil
+}
+
+// UnmarshalText is the reverse of MarshalText
+func (id *ID) UnmarshalText(b []byte) error {
+	i, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: %q", ErrInvalidID, b)
+	}
+	*id = ID(i)
+	return nil
+}
+
+// Value implements the driver.Valuer, the ID is stored as an integer.
+func (id ID) Value() (driver.Value, error) {
+	return int64(id), nil
+}
+
+// Scan implements the sql.Scanner. The src can be an integer, a string or []byte. NULL becomes 0.
+func (id *ID) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*id = 0
+	case int64:
+		*id = ID(v)
+	case []byte:
+		return id.UnmarshalText(v)
+	case string:
+		return id.UnmarshalText([]byte(v))
+	default:
+		return fmt.Errorf("%w: can't scan %T into snowflake.ID", ErrInvalidID, src)
+	}
+	return nil
+}
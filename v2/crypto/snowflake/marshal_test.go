@@ -0,0 +1,81 @@
+package snowflake_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding"
+	"encoding/json"
+	"github.com/CarsonSlovoka/go-pkg/v2/crypto/snowflake"
+	"testing"
+)
+
+var (
+	_ json.Marshaler           = snowflake.ID(0)
+	_ json.Unmarshaler         = (*snowflake.ID)(nil)
+	_ encoding.TextMarshaler   = snowflake.ID(0)
+	_ encoding.TextUnmarshaler = (*snowflake.ID)(nil)
+	_ driver.Valuer            = snowflake.ID(0)
+	_ sql.Scanner              = (*snowflake.ID)(nil)
+)
+
+func TestJSON(t *testing.T) {
+	type Data struct {
+		ID   snowflake.ID            `json:"id"`
+		Ptr  *snowflake.ID           `json:"ptr"`
+		Refs map[snowflake.ID]string `json:"refs"`
+	}
+	id := snowflake.ID(1234567890123456789)
+	b, err := json.Marshal(Data{ID: id, Ptr: &id, Refs: map[snowflake.ID]string{id: "a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"id":"1234567890123456789","ptr":"1234567890123456789","refs":{"1234567890123456789":"a"}}`
+	if string(b) != expected {
+		t.Fatal(string(b))
+	}
+
+	var d Data
+	if err = json.Unmarshal(b, &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.ID != id || *d.Ptr != id || d.Refs[id] != "a" {
+		t.Fatalf("%+v", d)
+	}
+
+	// number也可以
+	d = Data{}
+	if err = json.Unmarshal([]byte(`{"id":1234567890123456789,"ptr":null}`), &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.ID != id || d.Ptr != nil {
+		t.Fatalf("%+v", d)
+	}
+
+	for _, s := range []string{`{"id":"abc"}`, `{"id":1.5}`, `{"id":"9223372036854775808"}`, `{"id":true}`} {
+		if err = json.Unmarshal([]byte(s), &d); err == nil {
+			t.Fatal(s)
+		}
+	}
+}
+
+func TestSQL(t *testing.T) {
+	id := snowflake.ID(1234567890123456789)
+	v, err := id.Value()
+	if err != nil || v != int64(1234567890123456789) {
+		t.Fatal(v, err)
+	}
+
+	var got snowflake.ID
+	for _, src := range []any{int64(1234567890123456789), "1234567890123456789", []byte("1234567890123456789")} {
+		got = 0
+		if err = got.Scan(src); err != nil || got != id {
+			t.Fatal(src, got, err)
+		}
+	}
+	if err = got.Scan(nil); err != nil || got != 0 {
+		t.Fatal(got, err)
+	}
+	if err = got.Scan(1.5); err == nil {
+		t.Fatal("float")
+	}
+}
@@ -0,0 +1,57 @@
+package snowflake
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrClockBackward is returned when the clock moved backwards and the policy could not deal with it
+var ErrClockBackward = errors.New("clock moved backwards")
+
+// BackwardPolicy decides what the node does when the clock moves backwards (NTP step, VM resume, ...)
+type BackwardPolicy uint8
+
+const (
+	BackwardWait   BackwardPolicy = iota // 等到時間追上上一次生成的時間 (預設)
+	BackwardError                        // 直接回傳 ErrClockBackward
+	BackwardBorrow                       // 沿用上一次的時間，借用流水號的空間；流水號用完就再往後借一個時間單位
+)
+
+// Clock provides the current time to the Node. Tests can use a fake one to control the time.
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time        { return time.Now() }
+func (systemClock) Sleep(d time.Duration) { time.Sleep(d) }
+
+// Option configures a Node when it is created
+type Option func(n *Node)
+
+// WithBackwardPolicy sets the BackwardPolicy. maxWait is only used by BackwardWait,
+// if the clock is behind more than maxWait, ErrClockBackward is returned instead of waiting. 0 means no limit.
+func WithBackwardPolicy(policy BackwardPolicy, maxWait time.Duration) Option {
+	return func(n *Node) {
+		n.policy = policy
+		n.maxWait = maxWait
+	}
+}
+
+// WithClock replaces the real clock
+func WithClock(clock Clock) Option {
+	return func(n *Node) {
+		if clock != nil {
+			n.clock = clock
+		}
+	}
+}
+
+// WithUnit sets the Layout.Unit
+func WithUnit(unit time.Duration) Option {
+	return func(n *Node) {
+		n.layout.Unit = unit
+	}
+}
@@ -0,0 +1,154 @@
+package snowflake
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// rollback 模擬時間倒退: 讓節點以為上一次生成的時間在未來
+func rollback(n *Node, d time.Duration) {
+	n.mutex.Lock()
+	n.time = n.elapsed() + int64(d/n.unit)
+	n.mutex.Unlock()
+}
+
+func TestBackwardPolicy(t *testing.T) {
+	baseT := time.Date(2022, 7, 1, 16, 10, 54, 0, time.UTC)
+
+	// BackwardError
+	n, _ := NewNode(1, baseT, 10, 12, WithBackwardPolicy(BackwardError, 0))
+	rollback(n, time.Hour)
+	if _, err := n.GenerateE(); !errors.Is(err, ErrClockBackward) {
+		t.Fatal(err)
+	}
+
+	// BackwardBorrow: 時間沿用上一次的，流水號往上加
+	n, _ = NewNode(1, baseT, 10, 2, WithBackwardPolicy(BackwardBorrow, 0))
+	rollback(n, time.Hour)
+	last := n.time
+	var prev ID
+	for i := 0; i < 10; i++ { // 流水號只有2碼，會借用到下一個時間單位
+		id, err := n.GenerateE()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id <= prev {
+			t.Fatalf("%d <= %d", id, prev)
+		}
+		prev = id
+	}
+	if n.time != last+2 {
+		t.Fatal(n.time - last)
+	}
+
+	// BackwardWait: 超過maxWait
+	n, _ = NewNode(1, baseT, 10, 12, WithBackwardPolicy(BackwardWait, 10*time.Millisecond))
+	rollback(n, time.Hour)
+	if _, err := n.GenerateE(); !errors.Is(err, ErrClockBackward) {
+		t.Fatal(err)
+	}
+
+	// BackwardWait: 等時間追上
+	n, _ = NewNode(1, baseT, 10, 12)
+	rollback(n, 30*time.Millisecond)
+	last = n.time
+	start := time.Now()
+	id := n.Generate()
+	if time.Since(start) < 25*time.Millisecond {
+		t.Fatal("should wait")
+	}
+	if id>>n.shiftTime < ID(last) {
+		t.Fatal("duplicate time")
+	}
+}
+
+func TestGeneratePanic(t *testing.T) {
+	n, _ := NewNode(1, time.Now(), 10, 12, WithBackwardPolicy(BackwardError, 0))
+	rollback(n, time.Hour)
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatal("should panic")
+		}
+	}()
+	n.Generate()
+}
+
+// fakeClock 不會真的睡覺，Sleep只是把時間往後調
+type fakeClock struct {
+	now   time.Time
+	slept time.Duration
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.slept += d
+	c.now = c.now.Add(d)
+}
+
+func TestWithClock(t *testing.T) {
+	baseT := time.Date(2022, 7, 1, 16, 10, 54, 0, time.UTC)
+	clock := &fakeClock{now: baseT.Add(time.Hour)}
+	n, err := NewNode(3, baseT, 10, 4, WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 流水號只有4碼(16個)，時間不動的情況下生成100個，一定會用到Sleep
+	var prev ID
+	for i := 0; i < 100; i++ {
+		id := n.Generate()
+		if id <= prev {
+			t.Fatalf("%d <= %d", id, prev)
+		}
+		prev = id
+	}
+	if clock.slept != 6*time.Millisecond { // 100/16 => 第7個單位
+		t.Fatal(clock.slept)
+	}
+	if got := prev.Time(baseT, n.ShiftTime()); !got.Equal(clock.now) {
+		t.Fatal(got, clock.now)
+	}
+
+	// 時間倒退
+	clock.now = clock.now.Add(-5 * time.Millisecond)
+	id := n.Generate()
+	if id <= prev || clock.slept != 11*time.Millisecond {
+		t.Fatal(clock.slept)
+	}
+}
+
+func TestWithUnit(t *testing.T) {
+	baseT := time.Date(2022, 7, 1, 16, 10, 54, 0, time.UTC)
+	for _, unit := range []time.Duration{time.Millisecond, 10 * time.Millisecond, time.Second} {
+		clock := &fakeClock{now: baseT.Add(time.Hour + unit*3/2)} // 多出的半個單位會被捨去
+		n, err := NewNode(3, baseT, 16, 8, WithClock(clock), WithUnit(unit))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n.Layout().TimeUnit() != unit {
+			t.Fatal()
+		}
+		id := n.Generate()
+		want := baseT.Add(time.Hour + unit)
+		if got := n.Layout().Decompose(id).Time; !got.Equal(want) {
+			t.Fatal(unit, got)
+		}
+		if got := id.Time(baseT, n.ShiftTime(), unit); !got.Equal(want) {
+			t.Fatal(unit, got)
+		}
+
+		// 流水號用完就要睡到下一個單位
+		for i := 0; i < 256; i++ {
+			n.Generate()
+		}
+		if clock.slept != unit/2 {
+			t.Fatal(unit, clock.slept)
+		}
+	}
+
+	if _, err := NewNode(0, baseT, 10, 12, WithUnit(-time.Second)); err == nil {
+		t.Fatal("negative unit")
+	}
+}
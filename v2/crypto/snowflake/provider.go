@@ -0,0 +1,298 @@
+package snowflake
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NodeProvider returns a node number that fits in numNodeBits.
+// NodeFromHostname, NodeFromMAC, NodeFromPrivateIPv4 can be used directly, NodeFromEnv and Lease.Provider create one.
+type NodeProvider func(numNodeBits uint8) (int64, error)
+
+// NewNodeWithProvider is the same as NewNodeWithLayout, but the node number comes from the provider
+func NewNodeWithProvider(layout Layout, provider NodeProvider, opts ...Option) (*Node, error) {
+	if err := layout.Validate(); err != nil {
+		return nil, err
+	}
+	psw, err := provider(layout.NodeBits)
+	if err != nil {
+		return nil, err
+	}
+	return NewNodeWithLayout(psw, layout, opts...)
+}
+
+// maxNode 機器碼的最大可生成數值
+func maxNode(numNodeBits uint8) (int64, error) {
+	if numNodeBits >= 63 {
+		return 0, fmt.Errorf("numNodeBits must be less than 63, got %d", numNodeBits)
+	}
+	return NewLayout(time.Time{}, numNodeBits, 0).MaxNode(), nil
+}
+
+// checkNode 確認機器碼是否在範圍內
+func checkNode(node int64, numNodeBits uint8) (int64, error) {
+	nodeMax, err := maxNode(numNodeBits)
+	if err != nil {
+		return 0, err
+	}
+	if node < 0 || node > nodeMax {
+		return 0, fmt.Errorf("node number %d does not fit in %d bits, it must be between 0 and %d", node, numNodeBits, nodeMax)
+	}
+	return node, nil
+}
+
+// hashNode 用FNV-1a把資料壓到numNodeBits之內
+func hashNode(data []byte, numNodeBits uint8) (int64, error) {
+	nodeMax, err := maxNode(numNodeBits)
+	if err != nil {
+		return 0, err
+	}
+	h := fnv.New64a()
+	_, _ = h.Write(data)
+	return int64(h.Sum64() & uint64(nodeMax)), nil
+}
+
+// NodeFromHostname returns the hash of the hostname. The hash always fits in numNodeBits, but different hosts may collide.
+func NodeFromHostname(numNodeBits uint8) (int64, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return 0, err
+	}
+	return hashNode([]byte(hostname), numNodeBits)
+}
+
+// NodeFromMAC returns the hash of the first hardware address which is not a loopback.
+func NodeFromMAC(numNodeBits uint8) (int64, error) {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return 0, err
+	}
+	for _, i := range interfaces {
+		if i.Flags&net.FlagLoopback != 0 || len(i.HardwareAddr) == 0 {
+			continue
+		}
+		return hashNode(i.HardwareAddr, numNodeBits)
+	}
+	return 0, errors.New("no hardware address found")
+}
+
+// NodeFromPrivateIPv4 returns the low numNodeBits bits of the first private IPv4 address (10/8, 172.16/12, 192.168/16).
+// In the same subnet, make sure that the host part is not longer than numNodeBits, or the nodes may collide.
+func NodeFromPrivateIPv4(numNodeBits uint8) (int64, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return 0, err
+	}
+	return nodeFromIPv4(addrs, numNodeBits)
+}
+
+func nodeFromIPv4(addrs []net.Addr, numNodeBits uint8) (int64, error) {
+	if numNodeBits > 32 {
+		return 0, fmt.Errorf("an IPv4 address only has 32 bits, got numNodeBits %d", numNodeBits)
+	}
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		ip := ipNet.IP.To4()
+		if ip == nil || !ip.IsPrivate() {
+			continue
+		}
+		return int64(binary.BigEndian.Uint32(ip)) & (-1 ^ (-1 << numNodeBits)), nil
+	}
+	return 0, errors.New("no private IPv4 address found")
+}
+
+// NodeFromEnv reads the node number from the environment variable
+func NodeFromEnv(key string) NodeProvider {
+	return func(numNodeBits uint8) (int64, error) {
+		val, ok := os.LookupEnv(key)
+		if !ok {
+			return 0, fmt.Errorf("environment variable %s is not set", key)
+		}
+		node, err := strconv.ParseInt(strings.TrimSpace(val), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("environment variable %s: %w", key, err)
+		}
+		return checkNode(node, numNodeBits)
+	}
+}
+
+// ErrLeaseLost is returned by Lease.Release when the lock file has been taken over by another process
+var ErrLeaseLost = errors.New("the lease has been taken over by another process")
+
+// Lease is a node number claimed under a shared directory.
+// Each slot is a lock file "node-{number}.lock" which contains a unique token of the owner, its modification time is refreshed by a heartbeat.
+// A lock file which has not been refreshed for ttl is treated as abandoned and can be taken over.
+// If the process hangs longer than ttl and another process takes the slot over, the heartbeat notices it and closes Lost.
+type Lease struct {
+	node     int64
+	path     string
+	token    string
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+	lost     chan struct{}
+	lostOnce sync.Once
+}
+
+// AcquireLease claims the first free slot in dir. The heartbeat runs every ttl/3 until Release is called.
+// The ttl must be at least one millisecond.
+func AcquireLease(dir string, numNodeBits uint8, ttl time.Duration) (*Lease, error) {
+	if ttl < time.Millisecond { // 太短的話heartbeat的間隔會是0
+		return nil, fmt.Errorf("ttl must be at least 1ms, got %s", ttl)
+	}
+	if numNodeBits > 16 {
+		return nil, fmt.Errorf("numNodeBits %d is too large for file leases, the maximum is 16", numNodeBits)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	// 每個Lease都有不同的token，用來確認lock檔案是不是自己的
+	random := make([]byte, 8)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+	hostname, _ := os.Hostname()
+	token := hostname + ":" + strconv.Itoa(os.Getpid()) + ":" + hex.EncodeToString(random)
+	nodeMax := NewLayout(time.Time{}, numNodeBits, 0).MaxNode()
+	for node := int64(0); node <= nodeMax; node++ {
+		path := filepath.Join(dir, "node-"+strconv.FormatInt(node, 10)+".lock")
+		if !claim(path, token, ttl) {
+			continue
+		}
+		l := &Lease{node: node, path: path, token: token,
+			stop: make(chan struct{}), done: make(chan struct{}), lost: make(chan struct{})}
+		go l.heartbeat(ttl / 3)
+		return l, nil
+	}
+	return nil, fmt.Errorf("no free node number in %s, all %d slots are taken", dir, nodeMax+1)
+}
+
+// createLock 只有在檔案不存在的時候建立
+func createLock(path, token string) bool {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return false
+	}
+	_, err = f.WriteString(token)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return false
+	}
+	return true
+}
+
+// claim 建立lock檔案，如果檔案已經存在但過期了，就把它搶過來。
+// 不能直接刪除過期的檔案: 另一個process可能剛好已經刪除並建立了新的檔案，
+// 所以先改名為只有自己知道的名稱，確認改名的確實是過期的檔案之後才刪除
+func claim(path, token string, ttl time.Duration) bool {
+	for i := 0; i < 2; i++ {
+		if createLock(path, token) {
+			return true
+		}
+		if info, err := os.Stat(path); err != nil || time.Since(info.ModTime()) < ttl {
+			return false
+		}
+
+		stale := path + ".stale-" + token
+		if err := os.Rename(path, stale); err != nil {
+			continue // 已經被別人搶走了，再試一次(會因為檔案已經存在或還沒過期而失敗)
+		}
+		info, err := os.Stat(stale)
+		if err == nil && time.Since(info.ModTime()) < ttl {
+			// 改名到的是別人剛建立的檔案，還給它。如果已經有新的檔案，原本的擁有者的heartbeat會發現
+			_ = os.Link(stale, path)
+			_ = os.Remove(stale)
+			return false
+		}
+		_ = os.Remove(stale)
+	}
+	return false
+}
+
+func (l *Lease) markLost() {
+	l.lostOnce.Do(func() { close(l.lost) })
+}
+
+func (l *Lease) heartbeat(interval time.Duration) {
+	defer close(l.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case now := <-ticker.C:
+			b, err := os.ReadFile(l.path)
+			switch {
+			case os.IsNotExist(err):
+				// 可能是別人改名之後發現不是過期的檔案，正在還回來
+				_ = createLock(l.path, l.token)
+			case err != nil: // 下次再試
+			case string(b) == l.token:
+				_ = os.Chtimes(l.path, now, now)
+			default:
+				l.markLost()
+				return
+			}
+		}
+	}
+}
+
+// Lost is closed when the heartbeat finds that the lock file belongs to another process.
+// The node number must not be used after that.
+func (l *Lease) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// Node returns the claimed node number
+func (l *Lease) Node() int64 {
+	return l.node
+}
+
+// Provider returns a NodeProvider which gives the claimed node number
+func (l *Lease) Provider() NodeProvider {
+	return func(numNodeBits uint8) (int64, error) {
+		return checkNode(l.node, numNodeBits)
+	}
+}
+
+// Release stops the heartbeat and removes the lock file, the node number can be claimed by others after that.
+// ErrLeaseLost is returned and the file is kept if it belongs to another process.
+func (l *Lease) Release() error {
+	l.stopOnce.Do(func() { close(l.stop) })
+	<-l.done
+
+	// 一樣先改名再確認，避免刪除別人的檔案
+	released := l.path + ".release-" + l.token
+	if err := os.Rename(l.path, released); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if b, err := os.ReadFile(released); err != nil || string(b) != l.token {
+		_ = os.Link(released, l.path)
+		_ = os.Remove(released)
+		l.markLost()
+		return ErrLeaseLost
+	}
+	return os.Remove(released)
+}
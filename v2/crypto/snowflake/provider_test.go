@@ -0,0 +1,221 @@
+package snowflake
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHashNode(t *testing.T) {
+	a, err := hashNode([]byte("host-a"), 10)
+	if err != nil || a < 0 || a > 1023 {
+		t.Fatal(a, err)
+	}
+	if b, _ := hashNode([]byte("host-a"), 10); a != b {
+		t.Fatal("hash must be stable")
+	}
+	if _, err = hashNode([]byte("host-a"), 63); err == nil {
+		t.Fatal("63 bits")
+	}
+	if v, err := NodeFromHostname(10); err != nil || v > 1023 {
+		t.Fatal(v, err)
+	}
+}
+
+func TestNodeFromIPv4(t *testing.T) {
+	addrs := []net.Addr{
+		&net.IPNet{IP: net.ParseIP("127.0.0.1"), Mask: net.CIDRMask(8, 32)},
+		&net.IPNet{IP: net.ParseIP("8.8.8.8"), Mask: net.CIDRMask(24, 32)},
+		&net.IPNet{IP: net.ParseIP("fd00::1"), Mask: net.CIDRMask(64, 128)},
+		&net.IPNet{IP: net.ParseIP("10.1.2.3"), Mask: net.CIDRMask(8, 32)},
+	}
+	v, err := nodeFromIPv4(addrs, 10)
+	if err != nil || v != 2<<8|3 {
+		t.Fatal(v, err)
+	}
+	if v, _ = nodeFromIPv4(addrs, 32); v != 10<<24|1<<16|2<<8|3 {
+		t.Fatal(v)
+	}
+	if _, err = nodeFromIPv4(addrs, 33); err == nil {
+		t.Fatal("33 bits")
+	}
+	if _, err = nodeFromIPv4(addrs[:3], 10); err == nil {
+		t.Fatal("no private address")
+	}
+}
+
+func TestNodeFromEnv(t *testing.T) {
+	t.Setenv("SNOWFLAKE_NODE", " 12 ")
+	provider := NodeFromEnv("SNOWFLAKE_NODE")
+	if v, err := provider(4); err != nil || v != 12 {
+		t.Fatal(v, err)
+	}
+	if _, err := provider(3); err == nil {
+		t.Fatal("12 does not fit in 3 bits")
+	}
+
+	n, err := NewNodeWithProvider(NewLayout(time.Now(), 10, 12), provider)
+	if err != nil || n.node != 12 {
+		t.Fatal(err)
+	}
+
+	t.Setenv("SNOWFLAKE_NODE", "abc")
+	if _, err = provider(10); err == nil {
+		t.Fatal("not a number")
+	}
+	if _, err = NodeFromEnv("SNOWFLAKE_NODE_NOT_EXISTS")(10); err == nil {
+		t.Fatal("not set")
+	}
+}
+
+func TestAcquireLease(t *testing.T) {
+	dir := t.TempDir()
+	ttl := time.Minute
+
+	// 2 bits => 4個位置
+	var leases []*Lease
+	for i := 0; i < 4; i++ {
+		l, err := AcquireLease(dir, 2, ttl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if l.Node() != int64(i) {
+			t.Fatal(l.Node())
+		}
+		leases = append(leases, l)
+	}
+	if _, err := AcquireLease(dir, 2, ttl); err == nil {
+		t.Fatal("all slots are taken")
+	}
+	for _, ttl := range []time.Duration{-time.Second, 0, 2 * time.Nanosecond, time.Millisecond - 1} {
+		if _, err := AcquireLease(t.TempDir(), 2, ttl); err == nil {
+			t.Fatal(ttl)
+		}
+	}
+
+	// 釋放之後可以再次取得
+	if err := leases[1].Release(); err != nil {
+		t.Fatal(err)
+	}
+	l, err := AcquireLease(dir, 2, ttl)
+	if err != nil || l.Node() != 1 {
+		t.Fatal(err)
+	}
+	leases[1] = l
+
+	// 過期的lock檔案可以被搶走
+	_ = leases[2].Release()
+	path := filepath.Join(dir, "node-2.lock")
+	if err = os.WriteFile(path, []byte("dead"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * ttl)
+	_ = os.Chtimes(path, old, old)
+	if l, err = AcquireLease(dir, 2, ttl); err != nil || l.Node() != 2 {
+		t.Fatal(err)
+	}
+	leases[2] = l
+
+	n, err := NewNodeWithProvider(NewLayout(time.Now(), 2, 12), leases[3].Provider())
+	if err != nil || n.node != 3 {
+		t.Fatal(err)
+	}
+
+	for _, l = range leases {
+		if err = l.Release(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatal(entries)
+	}
+}
+
+func TestLeaseHeartbeat(t *testing.T) {
+	dir := t.TempDir()
+	l, err := AcquireLease(dir, 1, 30*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = l.Release() }()
+	path := filepath.Join(dir, "node-0.lock")
+	old := time.Now().Add(-time.Hour)
+	_ = os.Chtimes(path, old, old)
+	time.Sleep(50 * time.Millisecond)
+	info, err := os.Stat(path)
+	if err != nil || time.Since(info.ModTime()) > time.Minute {
+		t.Fatal("heartbeat should refresh the lock file", err)
+	}
+}
+
+func TestLeaseTakeoverRace(t *testing.T) {
+	// 只有1個位置，而且是過期的: 同時搶的時候只能有一個成功
+	for round := 0; round < 20; round++ {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "node-0.lock")
+		if err := os.WriteFile(path, []byte("dead"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		old := time.Now().Add(-time.Hour)
+		_ = os.Chtimes(path, old, old)
+
+		var (
+			wg     sync.WaitGroup
+			mu     sync.Mutex
+			leases []*Lease
+		)
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if l, err := AcquireLease(dir, 0, time.Minute); err == nil {
+					mu.Lock()
+					leases = append(leases, l)
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		if len(leases) != 1 {
+			t.Fatal("exactly one process can take the stale slot over", round, len(leases))
+		}
+		if err := leases[0].Release(); err != nil {
+			t.Fatal(err)
+		}
+		if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+			t.Fatal(entries)
+		}
+	}
+}
+
+func TestLeaseLost(t *testing.T) {
+	dir := t.TempDir()
+	l, err := AcquireLease(dir, 1, 30*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 模擬暫停太久之後，被其它process搶走
+	path := filepath.Join(dir, "node-0.lock")
+	if err = os.WriteFile(path, []byte("other"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-l.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("the heartbeat should notice that the lease is lost")
+	}
+	old := time.Now().Add(-time.Hour)
+	_ = os.Chtimes(path, old, old)
+	if err = l.Release(); !errors.Is(err, ErrLeaseLost) {
+		t.Fatal(err)
+	}
+	// 不能刪除或更新別人的檔案
+	info, err := os.Stat(path)
+	if b, _ := os.ReadFile(path); err != nil || string(b) != "other" || time.Since(info.ModTime()) < time.Minute {
+		t.Fatal("the lock file of the new owner must not be touched", err)
+	}
+}
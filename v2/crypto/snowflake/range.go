@@ -0,0 +1,73 @@
+package snowflake
+
+import (
+	"math/big"
+	"time"
+)
+
+// MaxTick 時間戳記能表示的最大值，超過就會溢位到符號位
+func (l Layout) MaxTick() int64 {
+	return -1 ^ (-1 << l.TimeBits())
+}
+
+// Tick returns how many time units t is after the epoch.
+// It is clamped to [0, MaxTick], the time before the epoch is 0 and after the end of life is MaxTick.
+func (l Layout) Tick(t time.Time) int64 {
+	// 用big.Int避免time.Duration在長單位(例如秒)的情況下溢位
+	ns := new(big.Int).SetInt64(t.Unix() - l.Epoch.Unix())
+	ns.Mul(ns, big.NewInt(int64(time.Second)))
+	ns.Add(ns, big.NewInt(int64(t.Nanosecond()-l.Epoch.Nanosecond())))
+	if ns.Sign() <= 0 {
+		return 0
+	}
+	tick := ns.Div(ns, big.NewInt(int64(l.TimeUnit())))
+	if !tick.IsInt64() || tick.Int64() > l.MaxTick() {
+		return l.MaxTick()
+	}
+	return tick.Int64()
+}
+
+// TimeAt returns the time of the tick, it's the reverse of Tick
+func (l Layout) TimeAt(tick int64) time.Time {
+	return l.timeAt(big.NewInt(tick))
+}
+
+func (l Layout) timeAt(tick *big.Int) time.Time {
+	ns := new(big.Int).Mul(tick, big.NewInt(int64(l.TimeUnit())))
+	sec, nsec := ns.DivMod(ns, big.NewInt(int64(time.Second)), new(big.Int))
+	return time.Unix(l.Epoch.Unix()+sec.Int64(), int64(l.Epoch.Nanosecond())+nsec.Int64()).In(l.Epoch.Location())
+}
+
+// MinIDAt returns the smallest ID that can be generated at t (the node and the step are 0)
+func (l Layout) MinIDAt(t time.Time) ID {
+	return ID(l.Tick(t) << l.ShiftTime())
+}
+
+// MaxIDAt returns the largest ID that can be generated at t (all bits of the node and the step are 1)
+func (l Layout) MaxIDAt(t time.Time) ID {
+	return ID(l.Tick(t)<<l.ShiftTime() | (-1 ^ (-1 << l.ShiftTime())))
+}
+
+// Range returns the IDs generated between from and to, both inclusive. It's for queries like:
+//
+//	SELECT * FROM t WHERE id BETWEEN minID AND maxID
+//
+// If from is after to, minID will be greater than maxID, which means nothing.
+func (l Layout) Range(from, to time.Time) (minID, maxID ID) {
+	return l.MinIDAt(from), l.MaxIDAt(to)
+}
+
+// MinIDAt see Layout.MinIDAt
+func (n *Node) MinIDAt(t time.Time) ID {
+	return n.layout.MinIDAt(t)
+}
+
+// MaxIDAt see Layout.MaxIDAt
+func (n *Node) MaxIDAt(t time.Time) ID {
+	return n.layout.MaxIDAt(t)
+}
+
+// Range see Layout.Range
+func (n *Node) Range(from, to time.Time) (minID, maxID ID) {
+	return n.layout.Range(from, to)
+}
@@ -0,0 +1,65 @@
+package snowflake_test
+
+import (
+	"github.com/CarsonSlovoka/go-pkg/v2/crypto/snowflake"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestLayout_MinMaxIDAt(t *testing.T) {
+	n, _ := snowflake.NewNode(123, BaseT, 10, 12)
+	before := time.Now()
+	id := n.Generate()
+	after := time.Now()
+
+	minID, maxID := n.Range(before, after)
+	if id < minID || id > maxID {
+		t.Fatalf("%d not in [%d, %d]", id, minID, maxID)
+	}
+
+	at := BaseT.Add(5 * time.Millisecond)
+	if v := n.MinIDAt(at); v != 5<<22 {
+		t.Fatal(v)
+	}
+	if v := n.MaxIDAt(at); v != 6<<22-1 {
+		t.Fatal(v)
+	}
+	if minID, maxID = n.Range(at, at.Add(-time.Millisecond)); minID <= maxID {
+		t.Fatal("from > to must be empty")
+	}
+
+	// 基準日之前
+	if v := n.MinIDAt(BaseT.Add(-time.Hour)); v != 0 {
+		t.Fatal(v)
+	}
+	// 超過年限
+	layout := n.Layout()
+	if v := n.MaxIDAt(BaseT.AddDate(1000, 0, 0)); v != math.MaxInt64 {
+		t.Fatal(v)
+	}
+	if v := layout.MinIDAt(BaseT.AddDate(1000, 0, 0)); v != snowflake.ID(layout.MaxTick()<<22) {
+		t.Fatal(v)
+	}
+}
+
+func TestLayout_Tick(t *testing.T) {
+	// 以秒為單位，41碼可以用到幾萬年後，time.Duration沒辦法表示
+	layout := snowflake.Layout{Epoch: BaseT, NodeBits: 10, StepBits: 12, Unit: time.Second}
+	at := BaseT.AddDate(5000, 0, 0)
+	tick := layout.Tick(at)
+	if !layout.TimeAt(tick).Equal(at) {
+		t.Fatal(layout.TimeAt(tick))
+	}
+	if layout.Tick(at.Add(999*time.Millisecond)) != tick {
+		t.Fatal("must be floored")
+	}
+
+	layout.Unit = 10 * time.Millisecond
+	if v := layout.Tick(BaseT.Add(25 * time.Millisecond)); v != 2 {
+		t.Fatal(v)
+	}
+	if v := layout.TimeAt(2); !v.Equal(BaseT.Add(20 * time.Millisecond)) {
+		t.Fatal(v)
+	}
+}
@@ -3,6 +3,7 @@ package snowflake
 
 import (
 	"errors"
+	"fmt"
 	"strconv"
 	"sync"
 	"time"
@@ -11,25 +12,28 @@ import (
 // A Node struct holds the basic information needed for a snowflake generator
 // [time][node][step]
 type Node struct {
-	baseTime *time.Time // 基準日
-	time     int64      // 生成的時間 與 基準日 相減的差(毫秒)
+	layout Layout // 基準日與各欄位的碼數
+	time   int64  // 生成的時間 與 基準日 相減的差(以layout.Unit為單位，預設為毫秒)
+	unit   time.Duration
+	clock  Clock
 
-	node        int64 // 機器碼 (隨便您設定，有點像Token的意思)
-	numNodeBits uint8 // 機器碼有幾碼
-	maskNode    int64 // 機器碼的遮罩
+	node     int64 // 機器碼 (隨便您設定，有點像Token的意思)
+	maskNode int64 // 機器碼的遮罩
 
-	step        int64 // 流水號
-	numStepBits uint8 // 流水號有幾碼
-	maskStep    int64 // 流水號遮罩
+	step     int64 // 流水號
+	maskStep int64 // 流水號遮罩
 
 	shiftTime uint8 // 時間戳記的位移碼位
 	shiftNode uint8 // 機器碼的位移碼位
 
+	policy  BackwardPolicy // 時間倒退時的處理方式
+	maxWait time.Duration  // BackwardWait最多等多久
+
 	mutex sync.Mutex // 為了不讓外部調用，用小寫命名
 }
 
 func (n *Node) BaseTime() time.Time {
-	return *n.baseTime
+	return n.layout.Epoch
 }
 
 func (n *Node) ShiftTime() uint8 {
@@ -48,18 +52,41 @@ func (n *Node) MaskStep() int64 {
 	return n.maskStep
 }
 
+// Layout returns the layout that the node was built from
+func (n *Node) Layout() Layout {
+	return n.layout
+}
+
 // NewNode returns a new snowflake node that can be used to generate snowflake IDs
-func NewNode(psw int64, baseTime time.Time, numNodeBits, numStepBits uint8) (*Node, error) {
-	var nodeMax int64
-	nodeMax = -1 ^ (-1 << numNodeBits) // 機器碼的最大可生成數值 // nodeNodeBits如果為5，表示需要二進制 11111
+func NewNode(psw int64, baseTime time.Time, numNodeBits, numStepBits uint8, opts ...Option) (*Node, error) {
+	return NewNodeWithLayout(psw, NewLayout(baseTime, numNodeBits, numStepBits), opts...)
+}
+
+// NewNodeWithLayout is the same as NewNode, but the configuration comes from the layout
+func NewNodeWithLayout(psw int64, layout Layout, opts ...Option) (*Node, error) {
 	n := Node{
-		baseTime:  &baseTime,
-		node:      psw,
-		maskNode:  nodeMax << numStepBits,
-		maskStep:  -1 ^ (-1 << numStepBits),
-		shiftTime: numNodeBits + numStepBits,
-		shiftNode: numStepBits,
+		layout: layout,
+		node:   psw,
+		clock:  systemClock{},
 	}
+	for _, opt := range opts {
+		opt(&n)
+	}
+
+	layout = n.layout // 有可能被Option改掉
+	if err := layout.Validate(); err != nil {
+		return nil, err
+	}
+	if err := layout.checkLifetime(n.clock.Now()); err != nil {
+		return nil, err
+	}
+	n.unit = layout.TimeUnit()
+	n.maskNode = layout.MaskNode()
+	n.maskStep = layout.MaskStep()
+	n.shiftTime = layout.ShiftTime()
+	n.shiftNode = layout.ShiftNode()
+
+	nodeMax := layout.MaxNode() // nodeNodeBits如果為5，表示需要二進制 11111
 
 	if n.node < 0 || n.node > nodeMax {
 		return nil, errors.New("Node number must be between 0 and " + strconv.FormatInt(nodeMax, 10))
@@ -68,19 +95,56 @@ func NewNode(psw int64, baseTime time.Time, numNodeBits, numStepBits uint8) (*No
 	return &n, nil
 }
 
+// Generate is the same as GenerateE, but panics if the ID cannot be generated.
+// It only happens when the clock moved backwards and the policy is BackwardError or the maxWait of BackwardWait is exceeded.
 func (n *Node) Generate() ID {
+	id, err := n.GenerateE()
+	if err != nil {
+		panic(err)
+	}
+	return id
+}
+
+// GenerateE creates the next ID. An error wrapping ErrClockBackward is returned when the clock moved backwards
+// and the BackwardPolicy can't handle it.
+func (n *Node) GenerateE() (ID, error) {
 	n.mutex.Lock()
 	defer n.mutex.Unlock()
+	return n.next()
+}
+
+// next 生成下一個ID，呼叫前必須先取得mutex
+func (n *Node) next() (ID, error) {
+	now := n.elapsed()
+	borrowed := false
+	if now < n.time { // 時間倒退了，如果直接使用會與已經發出去的ID重複
+		drift := time.Duration(n.time-now) * n.unit
+		switch n.policy {
+		case BackwardError:
+			return 0, fmt.Errorf("%w: %s", ErrClockBackward, drift)
+		case BackwardBorrow:
+			now = n.time
+			borrowed = true
+		default:
+			if n.maxWait > 0 && drift > n.maxWait {
+				return 0, fmt.Errorf("%w: %s exceeds the max wait %s", ErrClockBackward, drift, n.maxWait)
+			}
+			for now < n.time {
+				n.clock.Sleep(time.Duration(n.time-now) * n.unit)
+				now = n.elapsed()
+			}
+		}
+	}
 
-	baseTime := *n.baseTime
-	now := time.Since(baseTime).Milliseconds()
-	if now == n.time { // 如果當前時間與結點時間相同(毫秒)，用流水號來區別
+	if now == n.time { // 如果當前時間與結點時間相同(同一個時間單位)，用流水號來區別
 		n.step = (n.step + 1) & n.maskStep
 
-		if n.step == 0 { // +1之後如果又循環回來，我們就讓時間設定到下一毫秒
-			now += 1
-			if time.Since(baseTime.Add(time.Duration(now)*time.Millisecond)) < 0 {
-				time.Sleep(time.Millisecond)
+		if n.step == 0 { // +1之後如果又循環回來，我們就讓時間設定到下一個時間單位
+			now = n.time + 1
+			if !borrowed {
+				if d := n.layout.Epoch.Add(time.Duration(now) * n.unit).Sub(n.clock.Now()); d > 0 {
+					n.clock.Sleep(d)
+				}
 			}
 
 			/* 寫面這種寫法會多跑很多次
@@ -99,7 +163,12 @@ func (n *Node) Generate() ID {
 	return ID((now)<<n.shiftTime |
 		(n.node << n.shiftNode) |
 		(n.step),
-	)
+	), nil
+}
+
+// elapsed 與基準日相差了幾個時間單位
+func (n *Node) elapsed() int64 {
+	return int64(n.clock.Now().Sub(n.layout.Epoch) / n.unit)
 }
 
 // An ID is a custom type used for a snowflake ID.  This is used, so we can
@@ -121,9 +190,15 @@ func (id *ID) Base2() string {
 	return strconv.FormatInt(int64(*id), 2)
 }
 
-// Time returns an int64 unix timestamp in milliseconds of the snowflake ID time
-func (id *ID) Time(baseTime time.Time, shiftTime uint8) time.Time {
-	return baseTime.Add((time.Duration(*id) >> shiftTime) * time.Millisecond)
+// Time returns the time of the snowflake ID.
+// The timestamp is in milliseconds unless the unit is given (see Layout.Unit).
+func (id *ID) Time(baseTime time.Time, shiftTime uint8, unit ...time.Duration) time.Time {
+	l := Layout{Epoch: baseTime}
+	if len(unit) > 0 && unit[0] > 0 {
+		l.Unit = unit[0]
+	}
+	// 長單位(例如秒)乘上來會超過time.Duration，所以交給TimeAt處理
+	return l.TimeAt(int64(*id) >> shiftTime)
 }
 
 // Node returns an int64 of the snowflake ID node number
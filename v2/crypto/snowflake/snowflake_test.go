@@ -1,16 +1,14 @@
 package snowflake_test
 
 import (
+	"errors"
 	"github.com/CarsonSlovoka/go-pkg/v2/crypto/snowflake"
 	"testing"
 	"time"
 )
 
-var BaseT time.Time
-
-func init() {
-	BaseT = time.Date(2022, 7, 1, 16, 10, 54, 0, time.UTC)
-}
+// BaseT 用變數初始化而不是init，這樣其他檔案的init也能拿到正確的值
+var BaseT = time.Date(2022, 7, 1, 16, 10, 54, 0, time.UTC)
 
 func TestNewNode(t *testing.T) {
 	_, err := snowflake.NewNode(0, BaseT, 10, 12)
@@ -117,7 +115,7 @@ func TestOtherSize(t *testing.T) {
 		{0, 0, 0}, // Base2: 101101111001010010100011111
 		{2, 3, 0},
 		{0, 0, 5},
-		{67108862, 26, 10}, // String: 6536855424677705728 Base2: "101101010110111100100110101111111111111111111111111100000000000"
+		{4194302, 22, 0},
 	} {
 		n, err := snowflake.NewNode(d.psw, BaseT, d.numNode, d.numStep)
 		if err != nil {
@@ -130,6 +128,12 @@ func TestOtherSize(t *testing.T) {
 		*/
 	}
 
+	// 時間戳記只剩27碼(約37小時)，早就用完了
+	// String: 6536855424677705728 Base2: "101101010110111100100110101111111111111111111111111100000000000"
+	if _, err := snowflake.NewNode(67108862, BaseT, 26, 10); !errors.Is(err, snowflake.ErrLayoutExhausted) {
+		t.Fatal(err)
+	}
+
 	n1, _ := snowflake.NewNode(0, time.Date(2022, 7, 1, 16, 10, 54, 0, time.UTC), 0, 0)
 	id2022 := n1.Generate()
 	// t.Logf("%#v", id2022.Base2()) // 101110001100011100110100010
@@ -0,0 +1,336 @@
+package http
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache stores the downloaded files in a directory, and revalidates them with conditional requests.
+// The least recently used entries are evicted when the total size exceeds the limit.
+// A Cache can be shared by many downloads, see DownloadOptions.Cache
+type Cache struct {
+	dir     string
+	maxSize int64 // 0表示不限制
+
+	mu    sync.Mutex
+	locks map[string]*keyLock // key: 相同的URL同時只能有一個在使用，evict也不會刪除使用中的項目
+
+	now func() time.Time
+}
+
+// cacheEntry {key}.json 的內容，本體存放在 {key}.body
+type cacheEntry struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"lastModified,omitempty"`
+	Expires      time.Time `json:"expires"` // 在這之前不需要向伺服器確認
+}
+
+// NewCache creates the dir if it doesn't exist. maxSize is the limit of the total size of the bodies, 0 means unlimited.
+func NewCache(dir string, maxSize int64) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Cache{dir: dir, maxSize: maxSize, locks: map[string]*keyLock{}, now: time.Now}, nil
+}
+
+// Dir returns the cache directory
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// key 檔案名稱
+func (c *Cache) key(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *Cache) paths(url string) (body, meta string) {
+	key := c.key(url)
+	return filepath.Join(c.dir, key+".body"), filepath.Join(c.dir, key+".json")
+}
+
+// keyLock 每個key的鎖，沒有人使用的時候從locks刪除，避免map一直變大
+type keyLock struct {
+	sync.Mutex
+	refs int // 持有或等待的數量，在c.mu之下修改
+}
+
+// lock 鎖住url，回傳解鎖的函數
+func (c *Cache) lock(url string) func() {
+	key := c.key(url)
+	c.mu.Lock()
+	l, exists := c.locks[key]
+	if !exists {
+		l = &keyLock{}
+		c.locks[key] = l
+	}
+	l.refs++
+	c.mu.Unlock()
+	l.Lock()
+	return func() {
+		l.Unlock()
+		c.mu.Lock()
+		if l.refs--; l.refs == 0 {
+			delete(c.locks, key)
+		}
+		c.mu.Unlock()
+	}
+}
+
+func (c *Cache) load(url string) (*cacheEntry, bool) {
+	body, meta := c.paths(url)
+	b, err := os.ReadFile(meta)
+	if err != nil {
+		return nil, false
+	}
+	var entry cacheEntry
+	if err = json.Unmarshal(b, &entry); err != nil || entry.URL != url {
+		return nil, false
+	}
+	if _, err = os.Stat(body); err != nil {
+		return nil, false
+	}
+	return &entry, true
+}
+
+func (c *Cache) save(entry *cacheEntry) error {
+	_, meta := c.paths(entry.URL)
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(meta, b, 0644)
+}
+
+// Remove deletes the entry of the url
+func (c *Cache) Remove(url string) {
+	body, meta := c.paths(url)
+	_ = os.Remove(meta)
+	_ = os.Remove(body)
+}
+
+// cacheControl 回傳是否可以儲存，以及可以不用確認的時間
+func cacheControl(h http.Header) (store bool, maxAge time.Duration) {
+	store = true
+	noCache := false // 每次都要確認
+	for _, directive := range strings.Split(h.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "no-store":
+			store = false
+		case "no-cache":
+			noCache = true
+		case "max-age":
+			if sec, err := strconv.Atoi(strings.Trim(value, `"`)); err == nil && sec > 0 {
+				maxAge = time.Duration(sec) * time.Second
+			}
+		}
+	}
+	if noCache {
+		maxAge = 0
+	}
+	return
+}
+
+// open 取得url的內容，必要的時候向伺服器確認或重新下載。
+// 呼叫者要負責Close，不能存放的內容(no-store)會在Close的時候刪除
+func (c *Cache) open(ctx context.Context, o *DownloadOptions, url string, tr *tracker) (io.ReadCloser, error) {
+	defer c.lock(url)()
+	body, _ := c.paths(url)
+
+	entry, cached := c.load(url)
+	if cached && c.now().Before(entry.Expires) {
+		return c.hit(body, tr)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if cached {
+		if entry.ETag != "" {
+			req.Header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+	resp, err := o.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	store, maxAge := cacheControl(resp.Header)
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached:
+		if !store {
+			f, err := c.hit(body, tr)
+			c.Remove(url) // 已經開啟的檔案還是可以讀取
+			return f, err
+		}
+		entry.Expires = c.now().Add(maxAge)
+		if etag := resp.Header.Get("ETag"); etag != "" {
+			entry.ETag = etag
+		}
+		if err = c.save(entry); err != nil {
+			return nil, err
+		}
+		return c.hit(body, tr)
+	case resp.StatusCode != http.StatusOK:
+		return nil, newStatusError(resp)
+	}
+
+	tmp, err := os.CreateTemp(c.dir, "*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	tr.begin(0, resp.ContentLength)
+	_, err = io.Copy(tmp, io.TeeReader(o.limit(ctx, resp.Body), tr.writer()))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return nil, err
+	}
+	if !store {
+		c.Remove(url)
+		f, err := os.Open(tmp.Name())
+		if err != nil {
+			_ = os.Remove(tmp.Name())
+			return nil, err
+		}
+		return &tempFile{f}, nil
+	}
+
+	// 先改名本體再寫入meta，meta存在就表示本體是完整的 (windows不能改名已經開啟的檔案，所以先關閉)
+	_, meta := c.paths(url)
+	_ = os.Remove(meta)
+	if err = os.Rename(tmp.Name(), body); err != nil {
+		_ = os.Remove(tmp.Name())
+		return nil, err
+	}
+	if err = c.save(&cacheEntry{
+		URL:          url,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Expires:      c.now().Add(maxAge),
+	}); err != nil {
+		return nil, err
+	}
+	f, err := os.Open(body)
+	if err != nil {
+		return nil, err
+	}
+	c.evict()
+	return f, nil
+}
+
+// hit 使用快取的內容，並更新最後使用的時間
+func (c *Cache) hit(body string, tr *tracker) (io.ReadCloser, error) {
+	now := c.now()
+	_ = os.Chtimes(body, now, now)
+	f, err := os.Open(body)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := f.Stat(); err == nil {
+		tr.begin(info.Size(), info.Size())
+	}
+	return f, nil
+}
+
+// tempFile 關閉的時候刪除檔案
+type tempFile struct {
+	*os.File
+}
+
+func (f *tempFile) Close() error {
+	err := f.File.Close()
+	_ = os.Remove(f.Name())
+	return err
+}
+
+// Size returns the total size of the cached bodies
+func (c *Cache) Size() int64 {
+	var total int64
+	for _, e := range c.entries() {
+		total += e.size
+	}
+	return total
+}
+
+type cacheFile struct {
+	body    string
+	size    int64
+	modTime time.Time
+}
+
+func (c *Cache) entries() []cacheFile {
+	matches, _ := filepath.Glob(filepath.Join(c.dir, "*.body"))
+	files := make([]cacheFile, 0, len(matches))
+	for _, body := range matches {
+		if info, err := os.Stat(body); err == nil {
+			files = append(files, cacheFile{body, info.Size(), info.ModTime()})
+		}
+	}
+	return files
+}
+
+// evict 刪除最久沒有使用的項目，直到總大小不超過上限。
+// 使用中的項目(包含剛剛存入的)不會被刪除，否則另一個下載可能在load之後找不到本體
+func (c *Cache) evict() {
+	if c.maxSize <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	files := c.entries()
+	var total int64
+	for _, f := range files {
+		total += f.size
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+	for _, f := range files {
+		if total <= c.maxSize {
+			return
+		}
+		// 使用中(或等待中)的項目不刪除。持有c.mu的期間，其它人也無法開始使用
+		if _, inUse := c.locks[strings.TrimSuffix(filepath.Base(f.body), ".body")]; inUse {
+			continue
+		}
+		_ = os.Remove(strings.TrimSuffix(f.body, ".body") + ".json")
+		if os.Remove(f.body) == nil {
+			total -= f.size
+		}
+	}
+}
+
+// downloadCached 透過Cache下載到dst，不支援續傳與分段
+func (o *DownloadOptions) downloadCached(ctx context.Context, dst, url string, v *verifier, tr *tracker) error {
+	r, err := o.Cache.open(ctx, o, url, tr)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	part, meta := partPaths(dst)
+	_ = os.Remove(meta)
+	if err = writePart(part, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, r); err != nil {
+		_ = os.Remove(part)
+		return err
+	}
+	return finishPart(part, meta, dst, v)
+}
@@ -0,0 +1,328 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// cacheServer 依照路徑回傳不同的內容，ServeContent本身會處理If-None-Match與If-Modified-Since
+type cacheServer struct {
+	*httptest.Server
+	mu           sync.Mutex
+	content      map[string][]byte
+	cacheControl string
+	useETag      bool
+	requests     []*http.Request // 收到的請求
+	bodies       int             // 送出內容(200)的次數
+}
+
+var cacheModTime = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func newCacheServer(t *testing.T) *cacheServer {
+	s := &cacheServer{content: map[string][]byte{}, useETag: true}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests = append(s.requests, r)
+		data, exists := s.content[r.URL.Path]
+		if s.cacheControl != "" {
+			w.Header().Set("Cache-Control", s.cacheControl)
+		}
+		if s.useETag {
+			w.Header().Set("ETag", `"`+sha256Hex(data)[:16]+`"`)
+		}
+		s.mu.Unlock()
+		if !exists {
+			http.NotFound(w, r)
+			return
+		}
+		rec := &statusRecorder{ResponseWriter: w}
+		http.ServeContent(rec, r, "", cacheModTime, bytes.NewReader(data))
+		if rec.status == http.StatusOK {
+			s.mu.Lock()
+			s.bodies++
+			s.mu.Unlock()
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (s *cacheServer) counts() (requests, bodies int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.requests), s.bodies
+}
+
+func newTestCache(t *testing.T, maxSize int64) *Cache {
+	c, err := NewCache(filepath.Join(t.TempDir(), "cache"), maxSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestCache_Revalidate(t *testing.T) {
+	srv := newCacheServer(t)
+	srv.content["/a"] = testData
+	c := newTestCache(t, 0)
+	dir := t.TempDir()
+	opts := &DownloadOptions{Cache: c}
+
+	for i := 0; i < 3; i++ {
+		dst := filepath.Join(dir, "a"+string(rune('0'+i)))
+		if err := DownloadFileWithOptions(dst, srv.URL+"/a", opts); err != nil {
+			t.Fatal(err)
+		}
+		assertFile(t, dst, testData)
+	}
+	if requests, bodies := srv.counts(); requests != 3 || bodies != 1 {
+		t.Fatal("the body must be sent only once", requests, bodies)
+	}
+	if r := srv.requests[1]; r.Header.Get("If-None-Match") == "" || r.Header.Get("If-Modified-Since") == "" {
+		t.Fatal(r.Header)
+	}
+	if c.Size() != int64(len(testData)) {
+		t.Fatal(c.Size())
+	}
+
+	// 內容變了
+	srv.mu.Lock()
+	srv.content["/a"] = []byte("changed")
+	srv.mu.Unlock()
+	dst := filepath.Join(dir, "changed")
+	if err := DownloadFileWithOptions(dst, srv.URL+"/a", opts); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, dst, []byte("changed"))
+	if _, bodies := srv.counts(); bodies != 2 {
+		t.Fatal(bodies)
+	}
+}
+
+func TestCache_LastModified(t *testing.T) {
+	srv := newCacheServer(t)
+	srv.useETag = false
+	srv.content["/a"] = []byte("hello")
+	opts := &DownloadOptions{Cache: newTestCache(t, 0)}
+	for i := 0; i < 2; i++ {
+		dst := filepath.Join(t.TempDir(), "a")
+		if err := DownloadFileWithOptions(dst, srv.URL+"/a", opts); err != nil {
+			t.Fatal(err)
+		}
+		assertFile(t, dst, []byte("hello"))
+	}
+	if _, bodies := srv.counts(); bodies != 1 {
+		t.Fatal(bodies)
+	}
+	if r := srv.requests[1]; r.Header.Get("If-None-Match") != "" || r.Header.Get("If-Modified-Since") != cacheModTime.Format(http.TimeFormat) {
+		t.Fatal(r.Header)
+	}
+}
+
+func TestCache_MaxAge(t *testing.T) {
+	srv := newCacheServer(t)
+	srv.content["/a"] = []byte("hello")
+	srv.cacheControl = "public, max-age=60"
+	c := newTestCache(t, 0)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	opts := &DownloadOptions{Cache: c}
+	download := func() {
+		t.Helper()
+		dst := filepath.Join(t.TempDir(), "a")
+		if err := DownloadFileWithOptions(dst, srv.URL+"/a", opts); err != nil {
+			t.Fatal(err)
+		}
+		assertFile(t, dst, []byte("hello"))
+	}
+
+	download()
+	download() // 還沒過期，不需要確認
+	if requests, _ := srv.counts(); requests != 1 {
+		t.Fatal(requests)
+	}
+
+	now = now.Add(61 * time.Second)
+	download()
+	download() // 304之後重新計算期限
+	if requests, bodies := srv.counts(); requests != 2 || bodies != 1 {
+		t.Fatal(requests, bodies)
+	}
+}
+
+func TestCache_NoStore(t *testing.T) {
+	srv := newCacheServer(t)
+	srv.content["/a"] = []byte("secret")
+	srv.cacheControl = "no-store"
+	c := newTestCache(t, 0)
+	for i := 0; i < 2; i++ {
+		dst := filepath.Join(t.TempDir(), "a")
+		if err := DownloadFileWithOptions(dst, srv.URL+"/a", &DownloadOptions{Cache: c}); err != nil {
+			t.Fatal(err)
+		}
+		assertFile(t, dst, []byte("secret"))
+	}
+	if _, bodies := srv.counts(); bodies != 2 {
+		t.Fatal(bodies)
+	}
+	files, _ := os.ReadDir(c.Dir())
+	if len(files) != 0 {
+		t.Fatal("nothing should be stored", files)
+	}
+}
+
+func TestCache_Evict(t *testing.T) {
+	srv := newCacheServer(t)
+	for _, name := range []string{"a", "b", "c"} {
+		srv.content["/"+name] = bytes.Repeat([]byte(name), 100)
+	}
+	c := newTestCache(t, 250)
+	now := time.Now()
+	c.now = func() time.Time {
+		now = now.Add(time.Second) // 每次使用的時間都不同
+		return now
+	}
+	get := func(name string) {
+		t.Helper()
+		data, err := (&Downloader{Options: &DownloadOptions{Cache: c}}).Get(context.Background(), srv.URL+"/"+name)
+		if err != nil || string(data) != strings.Repeat(name, 100) {
+			t.Fatal(name, err)
+		}
+	}
+	cached := func(name string) bool {
+		_, ok := c.load(srv.URL + "/" + name)
+		return ok
+	}
+
+	get("a")
+	get("b")
+	get("a") // a 是最近使用的
+	get("c")
+	if !cached("a") || cached("b") || !cached("c") || c.Size() != 200 {
+		t.Fatal(cached("a"), cached("b"), cached("c"), c.Size())
+	}
+}
+
+func TestCache_Errors(t *testing.T) {
+	srv := newCacheServer(t)
+	srv.content["/a"] = []byte("hello")
+	c := newTestCache(t, 0)
+
+	dst := filepath.Join(t.TempDir(), "missing")
+	var statusErr *StatusError
+	if err := DownloadFileWithOptions(dst, srv.URL+"/missing", &DownloadOptions{Cache: c}); !errors.As(err, &statusErr) || fileExists(dst) {
+		t.Fatal(err)
+	}
+
+	// 快取的內容也要檢查
+	dst = filepath.Join(t.TempDir(), "a")
+	var checksumErr *ChecksumError
+	if err := DownloadFileWithOptions(dst, srv.URL+"/a", &DownloadOptions{
+		Cache:    c,
+		Checksum: &Checksum{Sum: sha256Hex([]byte("other"))},
+	}); !errors.As(err, &checksumErr) || fileExists(dst) {
+		t.Fatal(err)
+	}
+}
+
+func TestCacheControl(t *testing.T) {
+	for header, expected := range map[string]struct {
+		store  bool
+		maxAge time.Duration
+	}{
+		"":                        {true, 0},
+		"max-age=60":              {true, time.Minute},
+		"public, max-age=\"120\"": {true, 2 * time.Minute},
+		"no-cache, max-age=60":    {true, 0},
+		"no-store":                {false, 0},
+		"private, NO-STORE":       {false, 0},
+		"no-cache, no-store":      {false, 0},
+		"max-age=abc":             {true, 0},
+	} {
+		h := http.Header{}
+		h.Set("Cache-Control", header)
+		if store, maxAge := cacheControl(h); store != expected.store || maxAge != expected.maxAge {
+			t.Fatal(header, store, maxAge)
+		}
+	}
+}
+
+func TestCache_ConcurrentEvict(t *testing.T) {
+	srv := newCacheServer(t)
+	names := []string{"a", "b", "c", "d", "e", "f"}
+	for _, name := range names {
+		srv.content["/"+name] = bytes.Repeat([]byte(name), 100)
+	}
+	srv.cacheControl = "max-age=60" // 大部分都是直接使用快取，不需要確認
+	c := newTestCache(t, 250)       // 只能放2個，一直都在刪除
+	d := &Downloader{Options: &DownloadOptions{Cache: c}}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				name := names[(i+j)%len(names)]
+				data, err := d.Get(context.Background(), srv.URL+"/"+name)
+				if err != nil || string(data) != strings.Repeat(name, 100) {
+					t.Error(name, err)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	if size := c.Size(); size > 250+100*8 { // 使用中的項目不會被刪除，最多暫時超過
+		t.Fatal(size)
+	}
+	if len(c.locks) != 0 { // 用完的鎖要刪除
+		t.Fatal(len(c.locks))
+	}
+}
+
+func TestCache_EvictInUse(t *testing.T) {
+	srv := newCacheServer(t)
+	srv.content["/a"] = bytes.Repeat([]byte("a"), 100)
+	srv.content["/b"] = bytes.Repeat([]byte("b"), 100)
+	c := newTestCache(t, 150)
+	d := &Downloader{Options: &DownloadOptions{Cache: c}}
+	if _, err := d.Get(context.Background(), srv.URL+"/a"); err != nil {
+		t.Fatal(err)
+	}
+
+	// a 正在被另一個下載使用(已經load，還沒開啟本體)，存入b的時候不能刪除a
+	unlock := c.lock(srv.URL + "/a")
+	if _, err := d.Get(context.Background(), srv.URL+"/b"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.load(srv.URL + "/a"); !ok {
+		t.Fatal("the entry in use must not be evicted")
+	}
+	unlock()
+
+	// 不再使用之後，下一次可以刪除
+	c.evict()
+	if c.Size() > 150 {
+		t.Fatal(c.Size())
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"io/ioutil"
 	"net/http"
 	"os"
-	"reflect"
 	"time"
 )
 
@@ -26,11 +25,20 @@ func Download2Memory(url string) ([]byte, error) {
 }
 
 // DownloadFile https://stackoverflow.com/a/33853856/9935654
+// If out is a path, the download can be resumed, see DownloadFileWithOptions.
 func DownloadFile[T *os.File | string](out T, url string) (err error) {
 	return DownloadFileWithTimeout(out, url, 0)
 }
 
+// DownloadFileWithTimeout
+// If out is a path, it's the same as DownloadFileWithOptions with DownloadOptions.Timeout,
+// the data is written to "{out}.part" first and an interrupted download is resumed by the next call.
+// An *os.File is written directly and can't be resumed.
 func DownloadFileWithTimeout[T *os.File | string](out T, url string, timeout time.Duration) (err error) {
+	if dst, isPath := any(out).(string); isPath {
+		return DownloadFileWithOptions(dst, url, &DownloadOptions{Timeout: timeout})
+	}
+
 	// Get the data
 
 	// resp, err := http.Get(url) // 使用這種方法沒辦法設定timeout
@@ -52,20 +60,8 @@ func DownloadFileWithTimeout[T *os.File | string](out T, url string, timeout tim
 		return fmt.Errorf("bad status: %s", resp.Status)
 	}
 
-	// Prepare the output file.
-	var outFile *os.File
-	if reflect.TypeOf(out).String() == "string" {
-		outFile, err = os.Create(fmt.Sprintf("%v", out))
-		if err != nil {
-			return err
-		}
-		defer outFile.Close()
-	} else {
-		outFile = any(out).(*os.File)
-	}
-
 	// Writer the body to file
-	_, err = io.Copy(outFile, resp.Body)
+	_, err = io.Copy(any(out).(*os.File), resp.Body)
 	if err != nil {
 		return err
 	}
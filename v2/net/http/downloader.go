@@ -0,0 +1,241 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// StatusError is returned when the server responds with an unexpected status code
+type StatusError struct {
+	StatusCode int
+	Status     string
+	RetryAfter time.Duration // 伺服器指定的Retry-After，0表示沒有
+}
+
+func (e *StatusError) Error() string {
+	return "bad status: " + e.Status
+}
+
+// Temporary reports whether the request may succeed later: 429 or 5xx (except 501)
+func (e *StatusError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests ||
+		(e.StatusCode >= 500 && e.StatusCode != http.StatusNotImplemented)
+}
+
+func newStatusError(resp *http.Response) *StatusError {
+	return &StatusError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+}
+
+// parseRetryAfter "120" or "Wed, 21 Oct 2015 07:28:00 GMT"
+func parseRetryAfter(s string, now time.Time) time.Duration {
+	if s == "" {
+		return 0
+	}
+	if sec, err := strconv.Atoi(s); err == nil {
+		if sec < 0 {
+			return 0
+		}
+		return time.Duration(sec) * time.Second
+	}
+	if t, err := http.ParseTime(s); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+// Downloader downloads with retries. The zero value doesn't retry, use NewDownloader for the defaults.
+type Downloader struct {
+	// Options 下載檔案時的選項(續傳, 分段, 檢查, 進度)，nil表示使用零值。Client也是從這裡取得
+	Options *DownloadOptions
+
+	MaxRetries int           // 失敗之後最多重試幾次
+	MinBackoff time.Duration // 第一次重試之前的等待時間，之後每次加倍。0表示500ms
+	MaxBackoff time.Duration // 等待時間的上限(不包含Retry-After)，0表示30s
+
+	AttemptTimeout time.Duration // 每次嘗試的時限，0表示不限制
+	Timeout        time.Duration // 包含所有重試與等待的時限，0表示不限制
+
+	// OnRetry 在等待重試之前被呼叫，可以用來記錄
+	OnRetry func(attempt int, err error, delay time.Duration)
+}
+
+// NewDownloader returns a Downloader that retries 3 times
+func NewDownloader() *Downloader {
+	return &Downloader{
+		MaxRetries: 3,
+		MinBackoff: 500 * time.Millisecond,
+		MaxBackoff: 30 * time.Second,
+	}
+}
+
+func (d *Downloader) options() *DownloadOptions {
+	if d.Options == nil {
+		return &DownloadOptions{}
+	}
+	return d.Options
+}
+
+// Get downloads url to memory. Options.Cache is used if it's set.
+func (d *Downloader) Get(ctx context.Context, url string) (data []byte, err error) {
+	opts := d.options()
+	client := opts.client()
+	err = d.retry(ctx, func(ctx context.Context) error {
+		if opts.Cache != nil {
+			r, err := opts.Cache.open(ctx, opts, url, nil)
+			if err != nil {
+				return err
+			}
+			defer r.Close()
+			data, err = io.ReadAll(r)
+			return err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return newStatusError(resp)
+		}
+		data, err = io.ReadAll(opts.limit(ctx, resp.Body))
+		return err
+	})
+	return data, err
+}
+
+// Download downloads url to dst, see DownloadFileWithOptions.
+// The part file is kept between the attempts, so a retry continues from where it stopped if the server supports ranges.
+func (d *Downloader) Download(ctx context.Context, dst, url string) error {
+	opts := d.options()
+	return d.retry(ctx, func(ctx context.Context) error {
+		return opts.download(ctx, dst, url)
+	})
+}
+
+// retry 執行fn直到成功、遇到不能重試的錯誤、或者次數用完
+func (d *Downloader) retry(ctx context.Context, fn func(ctx context.Context) error) error {
+	if d.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.Timeout)
+		defer cancel()
+	}
+	for attempt := 0; ; attempt++ {
+		err := d.attempt(ctx, fn)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil { // 被取消或者整體的時限已到
+			return err
+		}
+		if attempt >= d.MaxRetries || !retryable(err) {
+			return err
+		}
+
+		delay := d.backoff(attempt)
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && statusErr.RetryAfter > delay {
+			delay = statusErr.RetryAfter
+		}
+		if d.OnRetry != nil {
+			d.OnRetry(attempt+1, err, delay)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w (last error: %v)", ctx.Err(), err)
+		case <-timer.C:
+		}
+	}
+}
+
+func (d *Downloader) attempt(ctx context.Context, fn func(ctx context.Context) error) error {
+	if d.AttemptTimeout <= 0 {
+		return fn(ctx)
+	}
+	ctx, cancel := context.WithTimeout(ctx, d.AttemptTimeout)
+	defer cancel()
+	return fn(ctx)
+}
+
+// backoff 指數增加，再加上抖動，避免大量的客戶端同時重試: [d/2, d)
+func (d *Downloader) backoff(attempt int) time.Duration {
+	minBackoff, maxBackoff := d.MinBackoff, d.MaxBackoff
+	if minBackoff <= 0 {
+		minBackoff = 500 * time.Millisecond
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = 30 * time.Second
+	}
+	delay := maxBackoff
+	if attempt < 32 {
+		if v := minBackoff << attempt; v > 0 && v < maxBackoff {
+			delay = v
+		}
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// retryable 429與5xx、傳輸中斷、逾時、連線被拒絕或重置可以重試。
+// client.Do的錯誤都是*url.Error(也符合net.Error)，所以要看裡面的原因，
+// 例如不支援的scheme、憑證錯誤、重新導向太多次，重試也沒有用
+func retryable(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Temporary()
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, context.DeadlineExceeded) {
+		return true // 每次嘗試的時限，整體的時限在retry已經先判斷了
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		if urlErr.Timeout() || errors.Is(urlErr.Err, io.EOF) { // 伺服器在回應之前關閉連線
+			return true
+		}
+		err = urlErr.Err
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTemporary
+	}
+	for _, errno := range []syscall.Errno{
+		syscall.ECONNRESET, syscall.ECONNREFUSED, syscall.ECONNABORTED,
+		syscall.EPIPE, syscall.ETIMEDOUT, syscall.EHOSTUNREACH, syscall.ENETUNREACH,
+	} {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+	// 其它連線、讀寫時的錯誤 (windows的errno與上面的不同)。
+	// tls的"remote error", "local error"(例如憑證錯誤)重試也沒有用
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		switch opErr.Op {
+		case "dial", "read", "write":
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,250 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// newFailingServer 前fails次回傳status，之後才正常
+func newFailingServer(t *testing.T, fails int32, status int, retryAfter string) (*httptest.Server, *int32) {
+	var count int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&count, 1) <= fails {
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			w.WriteHeader(status)
+			return
+		}
+		http.ServeContent(w, r, "data.bin", time.Time{}, bytes.NewReader(testData))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &count
+}
+
+func fastDownloader() *Downloader {
+	return &Downloader{MaxRetries: 3, MinBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+}
+
+func TestDownloader_Get(t *testing.T) {
+	srv, count := newFailingServer(t, 2, http.StatusServiceUnavailable, "")
+	d := fastDownloader()
+	var retries []int
+	d.OnRetry = func(attempt int, err error, delay time.Duration) {
+		retries = append(retries, attempt)
+	}
+	data, err := d.Get(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, testData) || atomic.LoadInt32(count) != 3 || len(retries) != 2 || retries[1] != 2 {
+		t.Fatal(atomic.LoadInt32(count), retries)
+	}
+}
+
+func TestDownloader_GiveUp(t *testing.T) {
+	srv, count := newFailingServer(t, 100, http.StatusBadGateway, "")
+	d := fastDownloader()
+	_, err := d.Get(context.Background(), srv.URL)
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusBadGateway {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(count) != 4 {
+		t.Fatal("1 + MaxRetries attempts are expected", atomic.LoadInt32(count))
+	}
+}
+
+func TestDownloader_NotRetryable(t *testing.T) {
+	for _, status := range []int{http.StatusNotFound, http.StatusForbidden, http.StatusNotImplemented} {
+		srv, count := newFailingServer(t, 100, status, "")
+		_, err := fastDownloader().Get(context.Background(), srv.URL)
+		var statusErr *StatusError
+		if !errors.As(err, &statusErr) || statusErr.StatusCode != status || atomic.LoadInt32(count) != 1 {
+			t.Fatal(status, err, atomic.LoadInt32(count))
+		}
+	}
+}
+
+func TestDownloader_PermanentError(t *testing.T) {
+	tlsSrv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer tlsSrv.Close()
+	redirectSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, r.URL.String(), http.StatusFound)
+	}))
+	defer redirectSrv.Close()
+
+	// 不支援的scheme、自簽的憑證、重新導向太多次: 只嘗試一次
+	for _, rawURL := range []string{"ftp://127.0.0.1/data.bin", tlsSrv.URL, redirectSrv.URL} {
+		d := fastDownloader()
+		attempts := 1
+		d.OnRetry = func(int, error, time.Duration) { attempts++ }
+		if _, err := d.Get(context.Background(), rawURL); err == nil || attempts != 1 {
+			t.Fatal(rawURL, attempts, err)
+		}
+	}
+}
+
+func TestDownloader_ConnectionRefused(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Close() // 之後的連線會被拒絕
+	d := fastDownloader()
+	attempts := 1
+	d.OnRetry = func(int, error, time.Duration) { attempts++ }
+	if _, err := d.Get(context.Background(), srv.URL); err == nil || attempts != 1+d.MaxRetries {
+		t.Fatal(attempts, err)
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	for _, test := range []struct {
+		err      error
+		expected bool
+	}{
+		{&StatusError{StatusCode: http.StatusServiceUnavailable}, true},
+		{&StatusError{StatusCode: http.StatusNotFound}, false},
+		{io.ErrUnexpectedEOF, true},
+		{&url.Error{Op: "Get", URL: "http://x", Err: io.EOF}, true},
+		{&url.Error{Op: "Get", URL: "http://x", Err: context.DeadlineExceeded}, true},
+		{&url.Error{Op: "Get", URL: "http://x", Err: &net.OpError{Op: "read", Err: syscall.ECONNRESET}}, true},
+		{&url.Error{Op: "Get", URL: "http://x", Err: &net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", IsNotFound: true}}}, false},
+		{&url.Error{Op: "Get", URL: "http://x", Err: &net.DNSError{Err: "server misbehaving", IsTemporary: true}}, true},
+		{&url.Error{Op: "Get", URL: "ftp://x", Err: errors.New(`unsupported protocol scheme "ftp"`)}, false},
+		{&url.Error{Op: "Get", URL: "https://x", Err: &net.OpError{Op: "remote error", Err: errors.New("tls: bad certificate")}}, false},
+		{&url.Error{Op: "Get", URL: "https://x", Err: &net.OpError{Op: "local error", Err: errors.New("tls: handshake failure")}}, false},
+		{&url.Error{Op: "Get", URL: "http://x", Err: &net.OpError{Op: "write", Err: errors.New("broken")}}, true},
+		{errors.New("disk full"), false},
+	} {
+		if got := retryable(test.err); got != test.expected {
+			t.Fatal(test.err, got)
+		}
+	}
+}
+
+func TestDownloader_RetryAfter(t *testing.T) {
+	srv, _ := newFailingServer(t, 1, http.StatusTooManyRequests, "1")
+	d := fastDownloader()
+	var delay time.Duration
+	d.OnRetry = func(attempt int, err error, d time.Duration) { delay = d }
+	start := time.Now()
+	if _, err := d.Get(context.Background(), srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	if delay != time.Second || time.Since(start) < time.Second {
+		t.Fatal("Retry-After must be honored", delay)
+	}
+}
+
+func TestDownloader_Download(t *testing.T) {
+	srv, ranges := newFlakyServer(t, `"v1"`)
+	dst := filepath.Join(t.TempDir(), "data.bin")
+	if err := fastDownloader().Download(context.Background(), dst, srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, dst, testData)
+	if len(*ranges) != 2 || (*ranges)[1] == "" {
+		t.Fatal("the retry should continue from the part file", *ranges)
+	}
+}
+
+func TestDownloader_Deadline(t *testing.T) {
+	var count int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&count, 1) == 1 {
+			select { // 第一次卡住
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	// 每次嘗試的時限到了之後重試
+	d := fastDownloader()
+	d.AttemptTimeout = 100 * time.Millisecond
+	data, err := d.Get(context.Background(), srv.URL)
+	if err != nil || string(data) != "ok" || atomic.LoadInt32(&count) != 2 {
+		t.Fatal(err, atomic.LoadInt32(&count))
+	}
+
+	// 整體的時限到了就不再重試
+	atomic.StoreInt32(&count, 0)
+	d = fastDownloader()
+	d.Timeout = 100 * time.Millisecond
+	start := time.Now()
+	if _, err = d.Get(context.Background(), srv.URL); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal(err)
+	}
+	if time.Since(start) > 2*time.Second || atomic.LoadInt32(&count) != 1 {
+		t.Fatal(atomic.LoadInt32(&count))
+	}
+}
+
+func TestDownloader_Cancel(t *testing.T) {
+	srv, count := newFailingServer(t, 100, http.StatusServiceUnavailable, "10")
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	if _, err := fastDownloader().Get(ctx, srv.URL); !errors.Is(err, context.Canceled) {
+		t.Fatal(err)
+	}
+	if time.Since(start) > 2*time.Second || atomic.LoadInt32(count) != 1 {
+		t.Fatal("it must stop waiting when the context is canceled")
+	}
+}
+
+func TestDownloader_Backoff(t *testing.T) {
+	d := &Downloader{MinBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	for attempt, expected := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		expected *= time.Millisecond
+		for i := 0; i < 20; i++ {
+			if got := d.backoff(attempt); got < expected/2 || got > expected {
+				t.Fatal(attempt, got)
+			}
+		}
+	}
+	if got := d.backoff(100); got < 500*time.Millisecond || got > time.Second {
+		t.Fatal(got)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2015, 10, 21, 7, 28, 0, 0, time.UTC)
+	for s, expected := range map[string]time.Duration{
+		"":                              0,
+		"120":                           2 * time.Minute,
+		"-1":                            0,
+		"abc":                           0,
+		"Wed, 21 Oct 2015 07:28:30 GMT": 30 * time.Second,
+		"Wed, 21 Oct 2015 07:27:00 GMT": 0,
+	} {
+		if got := parseRetryAfter(s, now); got != expected {
+			t.Fatal(s, got)
+		}
+	}
+}
+
+func ExampleDownloader() {
+	d := NewDownloader()
+	d.AttemptTimeout = time.Minute
+	d.Timeout = 10 * time.Minute
+	d.Options = &DownloadOptions{Progress: NewProgressBar(os.Stderr, 0).Update}
+	if err := d.Download(context.Background(), filepath.Join(os.TempDir(), "go.zip"), "https://go.dev/dl/go1.19.windows-amd64.zip"); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,427 @@
+package http
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// The archive formats of ExtractOptions.Format
+const (
+	FormatZip   = "zip"
+	FormatTar   = "tar"
+	FormatTarGz = "tar.gz"
+	FormatGzip  = "gzip" // 單一檔案
+)
+
+// The default limits of ExtractOptions
+const (
+	DefaultMaxExtractSize    = 4 << 30 // 4GiB
+	DefaultMaxExtractEntries = 100000
+)
+
+var (
+	ErrUnsafePath        = errors.New("unsafe path in archive")
+	ErrArchiveTooLarge   = errors.New("archive exceeds the size limit")
+	ErrTooManyEntries    = errors.New("archive exceeds the entry limit")
+	ErrUnknownArchive    = errors.New("unknown archive format")
+	ErrSymlinkNotAllowed = errors.New("symlinks are not allowed") // 請參考ExtractOptions.AllowSymlinks
+)
+
+// ExtractOptions 解壓縮的選項，零值即可使用
+type ExtractOptions struct {
+	// Download 下載的選項。有設定Checksum, Signature, Cache, Segments的時候，會先下載到暫存檔，檢查完之後才解壓縮
+	Download *DownloadOptions
+
+	Format          string // FormatZip, FormatTar, FormatTarGz, FormatGzip，空白表示依照內容判斷
+	StripComponents int    // 去掉路徑前面幾層，例如 "go/bin/go" 去掉1層為 "bin/go"
+
+	MaxSize    int64 // 解壓縮後的總大小上限，0表示DefaultMaxExtractSize，負數表示不限制
+	MaxEntries int   // 項目數量的上限，0表示DefaultMaxExtractEntries，負數表示不限制
+
+	AllowSymlinks bool // 是否建立symlink，目標只能是相對路徑而且不能包含".."
+}
+
+// DownloadAndExtract downloads url and extracts it into destDir while the data is streaming.
+// zip needs random access, so it's written to a temporary file first.
+// The entries that are absolute or contain ".." are rejected with ErrUnsafePath,
+// and ErrArchiveTooLarge or ErrTooManyEntries is returned when a limit is exceeded.
+// The files that have been extracted before an error are not removed.
+func DownloadAndExtract(url, destDir string, opts *ExtractOptions) error {
+	if opts == nil {
+		opts = &ExtractOptions{}
+	}
+	ctx := context.Background()
+	o := opts.Download
+	if o == nil {
+		o = &DownloadOptions{}
+	}
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return err
+	}
+	name := nameOf(url)
+
+	if o.Checksum != nil || o.Signature != nil || o.Cache != nil || o.Segments > 1 {
+		tmp, err := tempPath()
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmp)
+		if err = o.download(ctx, tmp, url); err != nil {
+			return err
+		}
+		f, err := os.Open(tmp)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return opts.extract(f, destDir, name)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := o.client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return newStatusError(resp)
+	}
+	tr := o.newTracker()
+	tr.begin(0, resp.ContentLength)
+	defer tr.end()
+	return opts.extract(io.TeeReader(o.limit(ctx, resp.Body), tr.writer()), destDir, name)
+}
+
+// nameOf 下載的檔名，用來判斷格式以及gzip的輸出名稱
+func nameOf(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil {
+		return path.Base(u.Path)
+	}
+	return path.Base(rawURL)
+}
+
+func tempPath() (string, error) {
+	f, err := os.CreateTemp("", "extract-*")
+	if err != nil {
+		return "", err
+	}
+	_ = f.Close()
+	return f.Name(), nil
+}
+
+// extractor 解壓縮時的狀態
+type extractor struct {
+	*ExtractOptions
+	destDir string
+	entries int
+	remain  int64 // 還可以寫入多少位元組，負數表示不限制
+}
+
+// extract 依照格式解壓縮。name是下載的檔名
+func (opts *ExtractOptions) extract(r io.Reader, destDir, name string) error {
+	e := &extractor{ExtractOptions: opts, destDir: destDir, remain: opts.MaxSize}
+	if e.remain == 0 {
+		e.remain = DefaultMaxExtractSize
+	}
+	br := bufio.NewReaderSize(r, 4096)
+	format := opts.Format
+	if format == "" {
+		format = sniffFormat(br, name)
+	}
+	switch format {
+	case FormatZip:
+		return e.extractZip(r, br)
+	case FormatTar:
+		return e.extractTar(tar.NewReader(br))
+	case FormatTarGz, FormatGzip:
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		gb := bufio.NewReaderSize(gz, 4096)
+		if format == FormatTarGz || (opts.Format == "" && isTar(gb, strings.TrimSuffix(name, ".gz"))) {
+			return e.extractTar(tar.NewReader(gb))
+		}
+		outName := path.Base(gz.Name) // 不使用header內的路徑
+		if gz.Name == "" {
+			outName = strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), ".gzip")
+		}
+		target, ok, err := e.target(outName)
+		if err != nil {
+			return err
+		}
+		if !ok { // 沒有任何輸出，不能當作成功
+			return fmt.Errorf("can't decide the output name of the gzip file %q", name)
+		}
+		return e.writeFile(target, gb, 0644, gz.ModTime)
+	default:
+		return ErrUnknownArchive
+	}
+}
+
+// sniffFormat 依照檔案的開頭判斷格式，看不出來的時候依照副檔名
+func sniffFormat(br *bufio.Reader, name string) string {
+	head, _ := br.Peek(262)
+	switch {
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")), bytes.HasPrefix(head, []byte("PK\x05\x06")):
+		return FormatZip
+	case bytes.HasPrefix(head, []byte{0x1f, 0x8b}):
+		return FormatGzip // extract再判斷是否為tar
+	case isTar(br, name):
+		return FormatTar
+	}
+	switch lower := strings.ToLower(name); {
+	case strings.HasSuffix(lower, ".zip"):
+		return FormatZip
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return FormatTarGz
+	case strings.HasSuffix(lower, ".gz"):
+		return FormatGzip
+	}
+	return ""
+}
+
+// isTar ustar與gnu格式在257的位置有"ustar"，舊的v7格式只能看副檔名
+func isTar(br *bufio.Reader, name string) bool {
+	if head, _ := br.Peek(262); len(head) == 262 && string(head[257:262]) == "ustar" {
+		return true
+	}
+	lower := strings.ToLower(name)
+	return strings.HasSuffix(lower, ".tar") || strings.HasSuffix(lower, ".tgz")
+}
+
+// target 計算項目的數量，並回傳實際的路徑
+func (e *extractor) target(name string) (target string, ok bool, err error) {
+	if e.MaxEntries >= 0 {
+		maxEntries := e.MaxEntries
+		if maxEntries == 0 {
+			maxEntries = DefaultMaxExtractEntries
+		}
+		if e.entries++; e.entries > maxEntries {
+			return "", false, ErrTooManyEntries
+		}
+	}
+	return e.resolve(name)
+}
+
+// resolve 檢查名稱，回傳實際的路徑。ok為false表示StripComponents之後沒有剩下的部分，略過該項目
+func (e *extractor) resolve(name string) (target string, ok bool, err error) {
+	rel, err := cleanName(name)
+	if err != nil {
+		return "", false, err
+	}
+	parts := strings.Split(rel, "/")
+	if len(parts) <= e.StripComponents {
+		return "", false, nil
+	}
+	rel = strings.Join(parts[e.StripComponents:], "/")
+	if rel == "" || rel == "." {
+		return "", false, nil
+	}
+	return filepath.Join(e.destDir, filepath.FromSlash(rel)), true, nil
+}
+
+// cleanName 不允許絕對路徑以及"..": 即使Clean之後在目錄內，經過symlink之後還是可能會跑到外面
+func cleanName(name string) (string, error) {
+	name = strings.ReplaceAll(name, `\`, "/") // windows建立的zip
+	if strings.HasPrefix(name, "/") || filepath.VolumeName(name) != "" || (len(name) > 1 && name[1] == ':') {
+		return "", fmt.Errorf("%w: %s", ErrUnsafePath, name)
+	}
+	for _, part := range strings.Split(name, "/") {
+		if part == ".." {
+			return "", fmt.Errorf("%w: %s", ErrUnsafePath, name)
+		}
+	}
+	return strings.TrimSuffix(path.Clean(name), "/"), nil
+}
+
+// writeFile 寫入檔案，並檢查大小的上限
+func (e *extractor) writeFile(target string, r io.Reader, mode fs.FileMode, modTime time.Time) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	_ = os.Remove(target) // 如果是symlink，不要寫到它指向的檔案
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return err
+	}
+	if e.remain >= 0 {
+		var n int64
+		n, err = io.CopyN(f, r, e.remain+1)
+		if err == io.EOF {
+			err = nil
+		}
+		if e.remain -= n; err == nil && e.remain < 0 {
+			err = ErrArchiveTooLarge
+		}
+	} else {
+		_, err = io.Copy(f, r)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(target)
+		return err
+	}
+	_ = os.Chmod(target, mode.Perm()) // OpenFile會受到umask的影響
+	if !modTime.IsZero() {
+		_ = os.Chtimes(target, modTime, modTime)
+	}
+	return nil
+}
+
+func (e *extractor) symlink(target, linkName string) error {
+	if !e.AllowSymlinks {
+		return fmt.Errorf("%w: %s", ErrSymlinkNotAllowed, target)
+	}
+	if _, err := cleanName(linkName); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	_ = os.Remove(target)
+	return os.Symlink(filepath.FromSlash(linkName), target)
+}
+
+func (e *extractor) extractTar(tr *tar.Reader) error {
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		target, ok, err := e.target(hdr.Name)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err = os.MkdirAll(target, hdr.FileInfo().Mode().Perm()|0700); err != nil {
+				return err
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			if err = e.writeFile(target, tr, hdr.FileInfo().Mode(), hdr.ModTime); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err = e.symlink(target, hdr.Linkname); err != nil {
+				return err
+			}
+		case tar.TypeLink:
+			src, ok, err := e.resolve(hdr.Linkname)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+			_ = os.Remove(target)
+			if err = os.Link(src, target); err != nil {
+				return err
+			}
+		default:
+			// 裝置檔、FIFO等等不處理
+		}
+	}
+}
+
+// extractZip zip需要ReaderAt，不是檔案的時候先寫到暫存檔
+func (e *extractor) extractZip(r io.Reader, br *bufio.Reader) error {
+	f, isFile := r.(*os.File)
+	if !isFile {
+		tmp, err := os.CreateTemp("", "extract-*.zip")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+		if _, err = io.Copy(tmp, br); err != nil {
+			return err
+		}
+		f = tmp
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	zr, err := zip.NewReader(f, info.Size())
+	if err != nil {
+		return err
+	}
+	for _, file := range zr.File {
+		target, ok, err := e.target(file.Name)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		mode := file.Mode()
+		switch {
+		case mode.IsDir():
+			if err = os.MkdirAll(target, mode.Perm()|0700); err != nil {
+				return err
+			}
+		case mode&fs.ModeSymlink != 0:
+			if err = e.extractZipSymlink(file, target); err != nil {
+				return err
+			}
+		default:
+			if e.remain >= 0 && file.UncompressedSize64 > uint64(e.remain) { // 先依照宣告的大小檢查
+				return ErrArchiveTooLarge
+			}
+			rc, err := file.Open()
+			if err != nil {
+				return err
+			}
+			if mode.Perm() == 0 { // 有些工具不會記錄權限
+				mode |= 0644
+			}
+			err = e.writeFile(target, rc, mode, file.Modified)
+			_ = rc.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (e *extractor) extractZipSymlink(file *zip.File, target string) error {
+	rc, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	linkName, err := io.ReadAll(io.LimitReader(rc, 4096))
+	if err != nil {
+		return err
+	}
+	return e.symlink(target, string(linkName))
+}
@@ -0,0 +1,317 @@
+package http
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// archiveEntry 測試用的壓縮檔項目，Link不是空白表示symlink
+type archiveEntry struct {
+	Name string
+	Body string
+	Mode fs.FileMode
+	Link string
+}
+
+var testEntries = []archiveEntry{
+	{Name: "root/", Mode: fs.ModeDir | 0755},
+	{Name: "root/a.txt", Body: "hello", Mode: 0644},
+	{Name: "root/bin/run.sh", Body: "#!/bin/sh\necho hi\n", Mode: 0755},
+	{Name: "root/empty/", Mode: fs.ModeDir | 0755},
+}
+
+func makeTar(t *testing.T, entries []archiveEntry) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.Name, Mode: int64(e.Mode.Perm()), Size: int64(len(e.Body)), ModTime: cacheModTime, Typeflag: tar.TypeReg}
+		switch {
+		case e.Mode.IsDir():
+			hdr.Typeflag = tar.TypeDir
+		case e.Link != "":
+			hdr.Typeflag, hdr.Linkname, hdr.Size = tar.TypeSymlink, e.Link, 0
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			_, _ = tw.Write([]byte(e.Body))
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func makeGzip(t *testing.T, name string, data []byte) []byte {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	gw.Name = name
+	_, _ = gw.Write(data)
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func makeZip(t *testing.T, entries []archiveEntry) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &zip.FileHeader{Name: e.Name, Method: zip.Deflate, Modified: cacheModTime}
+		hdr.SetMode(e.Mode)
+		body := e.Body
+		if e.Link != "" {
+			hdr.SetMode(fs.ModeSymlink | 0777)
+			body = e.Link
+		}
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = w.Write([]byte(body))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// newArchiveServer 依照路徑回傳files的內容
+func newArchiveServer(t *testing.T, files map[string][]byte) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, exists := files[r.URL.Path]
+		if !exists {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func assertExtracted(t *testing.T, dir string) {
+	t.Helper()
+	assertFile(t, filepath.Join(dir, "a.txt"), []byte("hello"))
+	assertFile(t, filepath.Join(dir, "bin", "run.sh"), []byte("#!/bin/sh\necho hi\n"))
+	if info, err := os.Stat(filepath.Join(dir, "empty")); err != nil || !info.IsDir() {
+		t.Fatal("the empty directory must be created", err)
+	}
+	if runtime.GOOS == "windows" {
+		return
+	}
+	for name, mode := range map[string]fs.FileMode{"a.txt": 0644, "bin/run.sh": 0755} {
+		info, err := os.Stat(filepath.Join(dir, name))
+		if err != nil || info.Mode().Perm() != mode {
+			t.Fatal(name, info.Mode(), err)
+		}
+		if !info.ModTime().Equal(cacheModTime) {
+			t.Fatal(name, info.ModTime())
+		}
+	}
+}
+
+func TestDownloadAndExtract(t *testing.T) {
+	tarData := makeTar(t, testEntries)
+	srv := newArchiveServer(t, map[string][]byte{
+		"/data.zip":    makeZip(t, testEntries),
+		"/data.tar":    tarData,
+		"/data.tar.gz": makeGzip(t, "", tarData),
+		"/zip":         makeZip(t, testEntries), // 沒有副檔名，依照內容判斷
+		"/tar":         tarData,
+		"/tgz":         makeGzip(t, "", tarData),
+	})
+	for _, name := range []string{"data.zip", "data.tar", "data.tar.gz", "zip", "tar", "tgz"} {
+		dir := filepath.Join(t.TempDir(), "out")
+		if err := DownloadAndExtract(srv.URL+"/"+name, dir, &ExtractOptions{StripComponents: 1}); err != nil {
+			t.Fatal(name, err)
+		}
+		assertExtracted(t, dir)
+	}
+
+	// 指定格式
+	dir := t.TempDir()
+	if err := DownloadAndExtract(srv.URL+"/tgz", dir, &ExtractOptions{Format: FormatTarGz}); err != nil {
+		t.Fatal(err)
+	}
+	assertExtracted(t, filepath.Join(dir, "root"))
+}
+
+func TestDownloadAndExtract_Gzip(t *testing.T) {
+	srv := newArchiveServer(t, map[string][]byte{
+		"/named.gz":    makeGzip(t, "dir/hello.txt", []byte("hello")),
+		"/data.txt.gz": makeGzip(t, "", []byte("data")),
+	})
+	dir := t.TempDir()
+	if err := DownloadAndExtract(srv.URL+"/named.gz", dir, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, filepath.Join(dir, "hello.txt"), []byte("hello"))
+	if err := DownloadAndExtract(srv.URL+"/data.txt.gz", dir, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, filepath.Join(dir, "data.txt"), []byte("data"))
+
+	// header與URL都沒有檔名
+	srv = newArchiveServer(t, map[string][]byte{"/": makeGzip(t, "", []byte("data"))})
+	dir = t.TempDir()
+	if err := DownloadAndExtract(srv.URL, dir, nil); err == nil {
+		t.Fatal("an empty extraction must fail")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatal(entries)
+	}
+}
+
+func TestDownloadAndExtract_UnsafePath(t *testing.T) {
+	for _, name := range []string{"../evil.txt", "/abs.txt", "a/../../evil.txt", `..\evil.txt`, "C:/evil.txt"} {
+		entries := []archiveEntry{{Name: name, Body: "x", Mode: 0644}}
+		srv := newArchiveServer(t, map[string][]byte{
+			"/a.zip": makeZip(t, entries),
+			"/a.tar": makeTar(t, entries),
+		})
+		root := t.TempDir()
+		for _, archive := range []string{"/a.zip", "/a.tar"} {
+			err := DownloadAndExtract(srv.URL+archive, filepath.Join(root, "out"), nil)
+			if !errors.Is(err, ErrUnsafePath) {
+				t.Fatal(name, archive, err)
+			}
+		}
+		if fileExists(filepath.Join(root, "evil.txt")) {
+			t.Fatal("the file must not be written outside")
+		}
+	}
+}
+
+func TestDownloadAndExtract_Symlink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need privileges on windows")
+	}
+	entries := []archiveEntry{
+		{Name: "a.txt", Body: "hello", Mode: 0644},
+		{Name: "link", Link: "a.txt"},
+	}
+	srv := newArchiveServer(t, map[string][]byte{
+		"/a.zip":    makeZip(t, entries),
+		"/a.tar":    makeTar(t, entries),
+		"/evil.tar": makeTar(t, []archiveEntry{{Name: "link", Link: "../../etc"}}),
+	})
+	for _, archive := range []string{"/a.zip", "/a.tar"} {
+		if err := DownloadAndExtract(srv.URL+archive, t.TempDir(), nil); !errors.Is(err, ErrSymlinkNotAllowed) {
+			t.Fatal(archive, err)
+		}
+		dir := t.TempDir()
+		if err := DownloadAndExtract(srv.URL+archive, dir, &ExtractOptions{AllowSymlinks: true}); err != nil {
+			t.Fatal(archive, err)
+		}
+		if target, err := os.Readlink(filepath.Join(dir, "link")); err != nil || target != "a.txt" {
+			t.Fatal(target, err)
+		}
+	}
+	if err := DownloadAndExtract(srv.URL+"/evil.tar", t.TempDir(), &ExtractOptions{AllowSymlinks: true}); !errors.Is(err, ErrUnsafePath) {
+		t.Fatal(err)
+	}
+}
+
+func TestDownloadAndExtract_Limits(t *testing.T) {
+	big := []archiveEntry{{Name: "zeros", Body: string(make([]byte, 1<<20)), Mode: 0644}}
+	srv := newArchiveServer(t, map[string][]byte{
+		"/big.zip":    makeZip(t, big),
+		"/big.tar.gz": makeGzip(t, "", makeTar(t, big)),
+		"/big.gz":     makeGzip(t, "zeros", make([]byte, 1<<20)),
+		"/data.zip":   makeZip(t, testEntries),
+	})
+	for _, archive := range []string{"/big.zip", "/big.tar.gz", "/big.gz"} {
+		dir := t.TempDir()
+		if err := DownloadAndExtract(srv.URL+archive, dir, &ExtractOptions{MaxSize: 1000}); !errors.Is(err, ErrArchiveTooLarge) {
+			t.Fatal(archive, err)
+		}
+		if fileExists(filepath.Join(dir, "zeros")) {
+			t.Fatal("the incomplete file must be removed")
+		}
+		if err := DownloadAndExtract(srv.URL+archive, dir, &ExtractOptions{MaxSize: -1}); err != nil {
+			t.Fatal(archive, err)
+		}
+	}
+	if err := DownloadAndExtract(srv.URL+"/data.zip", t.TempDir(), &ExtractOptions{MaxEntries: 3}); !errors.Is(err, ErrTooManyEntries) {
+		t.Fatal(err)
+	}
+	if err := DownloadAndExtract(srv.URL+"/data.zip", t.TempDir(), &ExtractOptions{MaxEntries: 4}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDownloadAndExtract_Checksum(t *testing.T) {
+	data := makeGzip(t, "", makeTar(t, testEntries))
+	srv := newArchiveServer(t, map[string][]byte{"/data.tgz": data})
+
+	dir := t.TempDir()
+	if err := DownloadAndExtract(srv.URL+"/data.tgz", dir, &ExtractOptions{
+		StripComponents: 1,
+		Download:        &DownloadOptions{Checksum: &Checksum{Sum: sha256Hex(data)}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	assertExtracted(t, dir)
+
+	// 檢查失敗的時候，不會解壓縮任何檔案
+	dir = t.TempDir()
+	var checksumErr *ChecksumError
+	if err := DownloadAndExtract(srv.URL+"/data.tgz", dir, &ExtractOptions{
+		Download: &DownloadOptions{Checksum: &Checksum{Sum: sha256Hex([]byte("x"))}},
+	}); !errors.As(err, &checksumErr) {
+		t.Fatal(err)
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 0 {
+		t.Fatal(files)
+	}
+}
+
+func TestDownloadAndExtract_Errors(t *testing.T) {
+	srv := newArchiveServer(t, map[string][]byte{"/data.bin": []byte("not an archive")})
+	if err := DownloadAndExtract(srv.URL+"/data.bin", t.TempDir(), nil); !errors.Is(err, ErrUnknownArchive) {
+		t.Fatal(err)
+	}
+	var statusErr *StatusError
+	if err := DownloadAndExtract(srv.URL+"/missing.zip", t.TempDir(), nil); !errors.As(err, &statusErr) {
+		t.Fatal(err)
+	}
+}
+
+func TestCleanName(t *testing.T) {
+	for name, expected := range map[string]string{
+		"a/b.txt":   "a/b.txt",
+		"./a//b/":   "a/b",
+		`dir\a.txt`: "dir/a.txt",
+	} {
+		if got, err := cleanName(name); err != nil || got != expected {
+			t.Fatal(name, got, err)
+		}
+	}
+}
+
+func ExampleDownloadAndExtract() {
+	// go/bin/go -> bin/go
+	if err := DownloadAndExtract("https://dl.google.com/go/go1.19.linux-amd64.tar.gz", filepath.Join(os.TempDir(), "go"), &ExtractOptions{
+		StripComponents: 1,
+		Download: &DownloadOptions{
+			Checksum: &Checksum{URL: "https://dl.google.com/go/go1.19.linux-amd64.tar.gz.sha256"},
+			Progress: NewProgressBar(os.Stderr, 0).Update,
+		},
+	}); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,399 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrDstConflict is the error of a Job whose Dst is the same as another Job with a different URL, the Job is not downloaded
+var ErrDstConflict = errors.New("another job with a different URL has the same destination")
+
+// Job is a file to download
+type Job struct {
+	URL      string
+	Dst      string
+	Checksum *Checksum // optional
+}
+
+// JobResult is the result of a Job
+type JobResult struct {
+	Job
+	Err     error
+	Size    int64 // 檔案大小
+	Elapsed time.Duration
+	Shared  bool // 相同的URL已經在下載，這個Job直接使用該結果
+}
+
+// Summary is returned by Manager.Run
+type Summary struct {
+	Results   []JobResult // 與jobs的順序相同
+	Succeeded int
+	Failed    int
+	Bytes     int64 // 所有成功的檔案大小總和，不包含Shared
+	Elapsed   time.Duration
+}
+
+// Failures returns the failed results
+func (s *Summary) Failures() []JobResult {
+	var failures []JobResult
+	for _, r := range s.Results {
+		if r.Err != nil {
+			failures = append(failures, r)
+		}
+	}
+	return failures
+}
+
+// Err returns nil if all jobs succeeded, otherwise an error that describes the first failure
+func (s *Summary) Err() error {
+	failures := s.Failures()
+	if len(failures) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d of %d downloads failed, %s: %w", len(failures), len(s.Results), failures[0].URL, failures[0].Err)
+}
+
+// AggregateProgress is the overall progress of Manager.Run
+type AggregateProgress struct {
+	Jobs      int // 全部的Job數量
+	Succeeded int
+	Failed    int
+	Active    int // 正在下載的數量
+
+	Downloaded int64   // 包含已經完成的
+	Rate       float64 // bytes/s, 正在下載的速度總和
+	Elapsed    time.Duration
+}
+
+// Manager downloads many files with Downloader.
+// The number of the concurrent downloads is limited in total and per host,
+// and the jobs with the same URL are downloaded only once, each of them is verified with its own Checksum.
+// A job whose Dst is already used by a job with another URL fails with ErrDstConflict.
+type Manager struct {
+	Downloader *Downloader // nil表示NewDownloader()，Options.Checksum與Options.Progress會被Job取代
+
+	Concurrency int // 同時下載的數量上限，0表示4
+	PerHost     int // 每個host同時下載的數量上限，0表示與Concurrency相同
+
+	// OnProgress 每個Job的進度，OnAggregate 整體的進度，都是每隔ProgressInterval通知一次
+	OnProgress       func(job Job, p Progress)
+	OnAggregate      func(AggregateProgress)
+	ProgressInterval time.Duration
+
+	// OnDone 每個Job完成的時候被呼叫，可能在不同的goroutine同時被呼叫
+	OnDone func(JobResult)
+
+	// DownloadRate 每個下載的速度上限(bytes/s)，0表示不限制。執行期間請使用SetDownloadRate修改。
+	// 整體的上限請在Downloader.Options.Limiters加上共用的Limiter
+	DownloadRate int64
+
+	mu       sync.Mutex
+	limiters map[*Limiter]struct{} // 正在下載的Job各自的Limiter
+}
+
+// SetDownloadRate changes the limit of each download, including the active ones. 0 means unlimited
+func (m *Manager) SetDownloadRate(bytesPerSecond int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.DownloadRate = bytesPerSecond
+	for l := range m.limiters {
+		l.SetRate(bytesPerSecond)
+	}
+}
+
+// newLimiter 建立Job的Limiter，即使目前沒有限制，之後也可以用SetDownloadRate調整
+func (m *Manager) newLimiter() (*Limiter, func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.limiters == nil {
+		m.limiters = map[*Limiter]struct{}{}
+	}
+	l := NewLimiter(m.DownloadRate)
+	m.limiters[l] = struct{}{}
+	return l, func() {
+		m.mu.Lock()
+		delete(m.limiters, l)
+		m.mu.Unlock()
+	}
+}
+
+// managerRun 執行期間的狀態
+type managerRun struct {
+	m     *Manager
+	start time.Time
+
+	mu       sync.Mutex
+	agg      AggregateProgress
+	active   map[int]Progress // 正在下載的Job的最新進度
+	finished int64            // 已經完成的位元組
+}
+
+// Run downloads the jobs and blocks until all of them are finished or ctx is done
+func (m *Manager) Run(ctx context.Context, jobs ...Job) *Summary {
+	r := &managerRun{m: m, start: time.Now(), active: map[int]Progress{}}
+	r.agg.Jobs = len(jobs)
+	summary := &Summary{Results: make([]JobResult, len(jobs))}
+
+	concurrency := m.Concurrency
+	if concurrency <= 0 {
+		concurrency = 4
+	}
+	perHost := m.PerHost
+	if perHost <= 0 || perHost > concurrency {
+		perHost = concurrency
+	}
+	sem := make(chan struct{}, concurrency)
+	hostSem := map[string]chan struct{}{}
+
+	// 相同的URL只下載一次，其它的Job等待第一個完成。
+	// 不同的URL不能寫到同一個Dst，否則會同時寫入同一個part檔案
+	groups := map[string][]int{}
+	dstURL := map[string]string{}
+	var leaders []int
+	for i, job := range jobs {
+		dst := job.Dst
+		if abs, err := filepath.Abs(dst); err == nil {
+			dst = abs
+		}
+		if u, exists := dstURL[dst]; exists && u != job.URL {
+			summary.Results[i] = r.done(JobResult{Job: job, Err: fmt.Errorf("%w: %s", ErrDstConflict, job.Dst)})
+			continue
+		}
+		dstURL[dst] = job.URL
+		if _, exists := groups[job.URL]; !exists {
+			leaders = append(leaders, i)
+		}
+		groups[job.URL] = append(groups[job.URL], i)
+		if host := hostOf(job.URL); hostSem[host] == nil {
+			hostSem[host] = make(chan struct{}, perHost)
+		}
+	}
+
+	stopAggregate := r.reportAggregate()
+
+	var wg sync.WaitGroup
+	for _, leader := range leaders {
+		wg.Add(1)
+		go func(leader int) {
+			defer wg.Done()
+			job := jobs[leader]
+			followers := groups[job.URL][1:]
+			// 先取得host的名額再取得整體的，避免佔用整體的名額而等待host。
+			// 有其它Job共用的時候，下載時不檢查第一個Job的Checksum，
+			// 否則它的Checksum錯誤會刪除檔案，讓其它的Job也失敗
+			res := r.acquire(ctx, hostSem[hostOf(job.URL)], sem, func() JobResult {
+				return r.download(ctx, leader, job, len(followers) == 0)
+			})
+			res.Job = job
+
+			// 下載失敗(連線、狀態碼等)，所有的Job都失敗；成功的話每個Job各自檢查自己的Checksum
+			kept := false // 是否有Dst相同的Job檢查成功
+			for _, i := range followers {
+				shared := JobResult{Job: jobs[i], Err: res.Err, Shared: true}
+				if res.Err == nil {
+					shared.Err = r.m.copyResult(ctx, jobs[i], job.Dst)
+					shared.Size = res.Size
+					kept = kept || (shared.Err == nil && sameFile(jobs[i].Dst, job.Dst))
+				}
+				summary.Results[i] = r.done(shared)
+			}
+			if res.Err == nil && len(followers) > 0 {
+				if res.Err = r.m.copyResult(ctx, job, job.Dst); res.Err != nil && !kept {
+					_ = os.Remove(job.Dst)
+				}
+			}
+			summary.Results[leader] = r.done(res)
+		}(leader)
+	}
+	wg.Wait()
+	stopAggregate()
+
+	for _, res := range summary.Results {
+		if res.Err != nil {
+			summary.Failed++
+			continue
+		}
+		summary.Succeeded++
+		if !res.Shared {
+			summary.Bytes += res.Size
+		}
+	}
+	summary.Elapsed = time.Since(r.start)
+	return summary
+}
+
+func sameFile(a, b string) bool {
+	if absA, err := filepath.Abs(a); err == nil {
+		a = absA
+	}
+	if absB, err := filepath.Abs(b); err == nil {
+		b = absB
+	}
+	return a == b
+}
+
+func hostOf(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil {
+		return u.Host
+	}
+	return rawURL
+}
+
+func (r *managerRun) acquire(ctx context.Context, hostSem, sem chan struct{}, fn func() JobResult) JobResult {
+	for _, s := range []chan struct{}{hostSem, sem} {
+		select {
+		case s <- struct{}{}:
+			defer func(s chan struct{}) { <-s }(s)
+		case <-ctx.Done():
+			return JobResult{Err: ctx.Err()}
+		}
+	}
+	return fn()
+}
+
+// download 下載job，verify為false的時候不檢查job.Checksum
+func (r *managerRun) download(ctx context.Context, i int, job Job, verify bool) JobResult {
+	d := NewDownloader()
+	if r.m.Downloader != nil {
+		copied := *r.m.Downloader
+		d = &copied
+	}
+	opts := *d.options()
+	opts.Checksum = nil
+	if verify {
+		opts.Checksum = job.Checksum
+	}
+	opts.Progress = nil
+	limiter, release := r.m.newLimiter()
+	defer release()
+	opts.Limiters = append(append([]*Limiter{}, opts.Limiters...), limiter)
+	if r.m.OnProgress != nil || r.m.OnAggregate != nil {
+		opts.ProgressInterval = r.m.ProgressInterval
+		opts.Progress = func(p Progress) {
+			r.mu.Lock()
+			if p.Finished {
+				delete(r.active, i)
+			} else {
+				r.active[i] = p
+			}
+			r.mu.Unlock()
+			if r.m.OnProgress != nil {
+				r.m.OnProgress(job, p)
+			}
+		}
+	}
+	d.Options = &opts
+
+	r.mu.Lock()
+	r.agg.Active++
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		r.agg.Active--
+		delete(r.active, i)
+		r.mu.Unlock()
+	}()
+
+	start := time.Now()
+	res := JobResult{Err: d.Download(ctx, job.Dst, job.URL)}
+	res.Elapsed = time.Since(start)
+	if res.Err == nil {
+		if info, err := os.Stat(job.Dst); err == nil {
+			res.Size = info.Size()
+		}
+	}
+	return res
+}
+
+// done 記錄結果
+func (r *managerRun) done(res JobResult) JobResult {
+	r.mu.Lock()
+	if res.Err != nil {
+		r.agg.Failed++
+	} else {
+		r.agg.Succeeded++
+		if !res.Shared {
+			r.finished += res.Size
+		}
+	}
+	r.mu.Unlock()
+	if r.m.OnDone != nil {
+		r.m.OnDone(res)
+	}
+	return res
+}
+
+// reportAggregate 定時通知整體的進度，回傳停止的函數(會送出最後一次的通知)
+func (r *managerRun) reportAggregate() (stop func()) {
+	if r.m.OnAggregate == nil {
+		return func() {}
+	}
+	interval := r.m.ProgressInterval
+	if interval <= 0 {
+		interval = DefaultProgressInterval
+	}
+	quit, exited := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(exited)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-quit:
+				return
+			case <-ticker.C:
+				r.m.OnAggregate(r.aggregate())
+			}
+		}
+	}()
+	return func() {
+		close(quit)
+		<-exited
+		r.m.OnAggregate(r.aggregate())
+	}
+}
+
+func (r *managerRun) aggregate() AggregateProgress {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	agg := r.agg
+	agg.Downloaded = r.finished
+	for _, p := range r.active {
+		agg.Downloaded += p.Downloaded
+		agg.Rate += p.Rate
+	}
+	agg.Elapsed = time.Since(r.start)
+	return agg
+}
+
+// copyResult 相同URL的Job，從已經下載好的檔案複製，並檢查自己的Checksum。
+// Dst相同的時候只檢查，不刪除檔案(第一個Job已經成功了)
+func (m *Manager) copyResult(ctx context.Context, job Job, src string) error {
+	opts := DownloadOptions{Checksum: job.Checksum}
+	if m.Downloader != nil && m.Downloader.Options != nil {
+		opts.Client = m.Downloader.Options.client()
+	}
+	v, err := opts.newVerifier(ctx, job.URL)
+	if err != nil {
+		return err
+	}
+	if sameFile(job.Dst, src) {
+		return v.check(src)
+	}
+	part, meta := partPaths(job.Dst)
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	if err = writePart(part, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, in); err != nil {
+		return err
+	}
+	return finishPart(part, meta, job.Dst, v)
+}
@@ -0,0 +1,259 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// concurrencyServer 記錄同時處理的請求數量的最大值，/missing 回傳404
+type concurrencyServer struct {
+	*httptest.Server
+	active, max, requests int32
+	mu                    sync.Mutex
+	paths                 map[string]int
+}
+
+func newConcurrencyServer(t *testing.T) *concurrencyServer {
+	s := &concurrencyServer{paths: map[string]int{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&s.requests, 1)
+		s.mu.Lock()
+		s.paths[r.URL.Path]++
+		s.mu.Unlock()
+		n := atomic.AddInt32(&s.active, 1)
+		defer atomic.AddInt32(&s.active, -1)
+		for {
+			m := atomic.LoadInt32(&s.max)
+			if n <= m || atomic.CompareAndSwapInt32(&s.max, m, n) {
+				break
+			}
+		}
+		time.Sleep(30 * time.Millisecond)
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeContent(w, r, "data.bin", time.Time{}, bytes.NewReader(testData))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestManager_Run(t *testing.T) {
+	srv1, srv2 := newConcurrencyServer(t), newConcurrencyServer(t)
+	dir := t.TempDir()
+	var jobs []Job
+	for i := 0; i < 6; i++ {
+		for j, srv := range []*concurrencyServer{srv1, srv2} {
+			name := "f" + string(rune('a'+i)) + string(rune('0'+j))
+			jobs = append(jobs, Job{URL: srv.URL + "/" + name, Dst: filepath.Join(dir, name)})
+		}
+	}
+	jobs = append(jobs, Job{URL: srv1.URL + "/missing", Dst: filepath.Join(dir, "missing")})
+
+	var done int32
+	m := &Manager{
+		Downloader:  &Downloader{},
+		Concurrency: 3,
+		PerHost:     2,
+		OnDone:      func(JobResult) { atomic.AddInt32(&done, 1) },
+	}
+	summary := m.Run(context.Background(), jobs...)
+	if summary.Succeeded != 12 || summary.Failed != 1 || int(done) != len(jobs) {
+		t.Fatal(summary.Succeeded, summary.Failed, done)
+	}
+	if summary.Bytes != 12*int64(len(testData)) {
+		t.Fatal(summary.Bytes)
+	}
+	if max := atomic.LoadInt32(&srv1.max); max > 2 {
+		t.Fatal("per host limit", max)
+	}
+	if max := srv1.max + srv2.max; max > 4 || atomic.LoadInt32(&srv1.max) < 2 {
+		t.Fatal(srv1.max, srv2.max)
+	}
+	for i, res := range summary.Results {
+		if res.URL != jobs[i].URL {
+			t.Fatal("the order must be kept")
+		}
+		if res.Err == nil {
+			assertFile(t, res.Dst, testData)
+		}
+	}
+
+	failures := summary.Failures()
+	var statusErr *StatusError
+	if len(failures) != 1 || !errors.As(failures[0].Err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Fatal(failures)
+	}
+	if err := summary.Err(); err == nil || !strings.Contains(err.Error(), "1 of 13 downloads failed") {
+		t.Fatal(err)
+	}
+}
+
+func TestManager_Concurrency(t *testing.T) {
+	srv := newConcurrencyServer(t)
+	dir := t.TempDir()
+	var jobs []Job
+	for i := 0; i < 8; i++ {
+		name := "f" + string(rune('a'+i))
+		jobs = append(jobs, Job{URL: srv.URL + "/" + name, Dst: filepath.Join(dir, name)})
+	}
+	summary := (&Manager{Concurrency: 2, PerHost: 5}).Run(context.Background(), jobs...)
+	if summary.Err() != nil || srv.max != 2 {
+		t.Fatal(summary.Err(), srv.max)
+	}
+}
+
+func TestManager_Deduplicate(t *testing.T) {
+	srv := newConcurrencyServer(t)
+	dir := t.TempDir()
+	sum := sha256Hex(testData)
+	wrong := sha256Hex([]byte("x"))
+	jobs := []Job{
+		{URL: srv.URL + "/a", Dst: filepath.Join(dir, "a1")},
+		{URL: srv.URL + "/a", Dst: filepath.Join(dir, "a2"), Checksum: &Checksum{Sum: sum}},
+		{URL: srv.URL + "/a", Dst: filepath.Join(dir, "a1")},
+		{URL: srv.URL + "/a", Dst: filepath.Join(dir, "a3"), Checksum: &Checksum{Sum: wrong}},
+		{URL: srv.URL + "/b", Dst: filepath.Join(dir, "b")},
+	}
+	summary := (&Manager{}).Run(context.Background(), jobs...)
+	if srv.paths["/a"] != 1 || srv.paths["/b"] != 1 {
+		t.Fatal("the same URL must be downloaded once", srv.paths)
+	}
+	if summary.Succeeded != 4 || summary.Failed != 1 || summary.Bytes != 2*int64(len(testData)) {
+		t.Fatal(summary.Succeeded, summary.Failed, summary.Bytes)
+	}
+	for _, i := range []int{1, 2, 3} {
+		if !summary.Results[i].Shared {
+			t.Fatal(i)
+		}
+	}
+	assertFile(t, jobs[1].Dst, testData)
+	var checksumErr *ChecksumError
+	if !errors.As(summary.Results[3].Err, &checksumErr) || fileExists(jobs[3].Dst) {
+		t.Fatal(summary.Results[3].Err)
+	}
+}
+
+func TestManager_Progress(t *testing.T) {
+	srv := newSlowServer(t, len(testData)/4, 20*time.Millisecond, true)
+	dir := t.TempDir()
+	jobs := []Job{
+		{URL: srv.URL + "/a", Dst: filepath.Join(dir, "a")},
+		{URL: srv.URL + "/b", Dst: filepath.Join(dir, "b")},
+	}
+	var mu sync.Mutex
+	perJob := map[string]int{}
+	var aggregates []AggregateProgress
+	m := &Manager{
+		ProgressInterval: 10 * time.Millisecond,
+		OnProgress: func(job Job, p Progress) {
+			mu.Lock()
+			perJob[job.URL]++
+			mu.Unlock()
+		},
+		OnAggregate: func(p AggregateProgress) {
+			aggregates = append(aggregates, p)
+		},
+	}
+	if err := m.Run(context.Background(), jobs...).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if perJob[jobs[0].URL] == 0 || perJob[jobs[1].URL] == 0 || len(aggregates) < 2 {
+		t.Fatal(perJob, len(aggregates))
+	}
+	final := aggregates[len(aggregates)-1]
+	if final.Jobs != 2 || final.Succeeded != 2 || final.Active != 0 || final.Downloaded != 2*int64(len(testData)) {
+		t.Fatal(final)
+	}
+}
+
+func TestManager_Cancel(t *testing.T) {
+	srv := newConcurrencyServer(t)
+	dir := t.TempDir()
+	var jobs []Job
+	for i := 0; i < 10; i++ {
+		name := "f" + string(rune('a'+i))
+		jobs = append(jobs, Job{URL: srv.URL + "/" + name, Dst: filepath.Join(dir, name)})
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	summary := (&Manager{Concurrency: 1}).Run(ctx, jobs...)
+	if summary.Failed != 10 || !errors.Is(summary.Results[0].Err, context.Canceled) {
+		t.Fatal(summary.Failed, summary.Results[0].Err)
+	}
+}
+
+func TestManager_SameDst(t *testing.T) {
+	srv := newConcurrencyServer(t)
+	dir := t.TempDir()
+	dst := filepath.Join(dir, "a")
+	jobs := []Job{
+		{URL: srv.URL + "/a", Dst: dst},
+		{URL: srv.URL + "/b", Dst: dst}, // 不同的URL，相同的Dst
+		{URL: srv.URL + "/a", Dst: filepath.Join(dir, ".", "a"), Checksum: &Checksum{Sum: sha256Hex([]byte("x"))}},
+		{URL: srv.URL + "/a", Dst: dst, Checksum: &Checksum{Sum: sha256Hex(testData)}},
+	}
+	summary := (&Manager{}).Run(context.Background(), jobs...)
+	if srv.paths["/b"] != 0 {
+		t.Fatal("the conflicting job must not be downloaded")
+	}
+	if !errors.Is(summary.Results[1].Err, ErrDstConflict) {
+		t.Fatal(summary.Results[1].Err)
+	}
+	// 相同的Dst也要檢查自己的Checksum，但不能刪除第一個Job的檔案
+	var checksumErr *ChecksumError
+	if !errors.As(summary.Results[2].Err, &checksumErr) {
+		t.Fatal(summary.Results[2].Err)
+	}
+	if summary.Results[0].Err != nil || summary.Results[3].Err != nil || summary.Succeeded != 2 || summary.Failed != 2 {
+		t.Fatal(summary.Results)
+	}
+	assertFile(t, dst, testData)
+}
+
+func TestManager_DifferentChecksum(t *testing.T) {
+	srv := newConcurrencyServer(t)
+	dir := t.TempDir()
+	wrong := &Checksum{Sum: sha256Hex([]byte("x"))}
+	for _, tc := range []struct {
+		name string
+		jobs []Job
+	}{
+		{"no checksum", []Job{
+			{URL: srv.URL + "/f", Dst: filepath.Join(dir, "a1"), Checksum: wrong},
+			{URL: srv.URL + "/f", Dst: filepath.Join(dir, "a2")},
+		}},
+		{"correct checksum", []Job{
+			{URL: srv.URL + "/f", Dst: filepath.Join(dir, "b1"), Checksum: wrong},
+			{URL: srv.URL + "/f", Dst: filepath.Join(dir, "b2"), Checksum: &Checksum{Sum: sha256Hex(testData)}},
+		}},
+		{"same dst", []Job{
+			{URL: srv.URL + "/f", Dst: filepath.Join(dir, "c"), Checksum: wrong},
+			{URL: srv.URL + "/f", Dst: filepath.Join(dir, "c"), Checksum: &Checksum{Sum: sha256Hex(testData)}},
+		}},
+	} {
+		summary := (&Manager{}).Run(context.Background(), tc.jobs...)
+		// 第一個Job的Checksum錯誤不能影響其它的Job
+		var checksumErr *ChecksumError
+		if !errors.As(summary.Results[0].Err, &checksumErr) || summary.Results[1].Err != nil {
+			t.Fatal(tc.name, summary.Results[0].Err, summary.Results[1].Err)
+		}
+		assertFile(t, tc.jobs[1].Dst, testData)
+		if tc.jobs[0].Dst != tc.jobs[1].Dst && fileExists(tc.jobs[0].Dst) {
+			t.Fatal(tc.name, "the file with the wrong checksum must be deleted")
+		}
+	}
+	if srv.paths["/f"] != 3 {
+		t.Fatal(srv.paths)
+	}
+}
@@ -0,0 +1,240 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// probe 用HEAD取得檔案大小以及是否支援Range
+type probe struct {
+	size      int64
+	ranges    bool
+	validator string
+}
+
+func (o *DownloadOptions) probe(ctx context.Context, url string) (*probe, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := o.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return &probe{size: -1}, nil // 有些伺服器不支援HEAD，當作不支援Range
+	}
+	return &probe{
+		size:      resp.ContentLength,
+		ranges:    resp.Header.Get("Accept-Ranges") == "bytes",
+		validator: validator(resp.Header),
+	}, nil
+}
+
+// segment 一段的範圍[Start, End]，Off是下一個要寫入的位置
+type segment struct {
+	Start int64 `json:"start"`
+	Off   int64 `json:"off"`
+	End   int64 `json:"end"`
+}
+
+// segmentState "{dst}.part.segments" 的內容，記錄各段的進度，失敗之後可以從中斷的地方繼續
+type segmentState struct {
+	Validator string     `json:"validator"`
+	Size      int64      `json:"size"`
+	Segments  []*segment `json:"segments"`
+}
+
+func segmentsPath(part string) string {
+	return part + ".segments"
+}
+
+// loadSegments 讀取之前的進度，檔案已經變了或part檔案不完整的時候回傳nil
+func loadSegments(part string, p *probe) []*segment {
+	b, err := os.ReadFile(segmentsPath(part))
+	if err != nil {
+		return nil
+	}
+	var state segmentState
+	if err = json.Unmarshal(b, &state); err != nil || state.Validator != p.validator || state.Size != p.size {
+		return nil
+	}
+	if info, err := os.Stat(part); err != nil || info.Size() != p.size {
+		return nil
+	}
+	// 各段必須依序涵蓋整個檔案
+	next := int64(0)
+	for _, seg := range state.Segments {
+		if seg == nil || seg.Start != next || seg.Off < seg.Start || seg.Off > seg.End+1 {
+			return nil
+		}
+		next = seg.End + 1
+	}
+	if next != p.size {
+		return nil
+	}
+	return state.Segments
+}
+
+// splitSegments 把size平均分成n段
+func splitSegments(size, n int64) []*segment {
+	if n > size {
+		n = size
+	}
+	segs := make([]*segment, n)
+	segSize := size / n
+	for i := int64(0); i < n; i++ {
+		start := i * segSize
+		end := start + segSize - 1
+		if i == n-1 {
+			end = size - 1
+		}
+		segs[i] = &segment{Start: start, Off: start, End: end}
+	}
+	return segs
+}
+
+// downloadParallel 把檔案分成多段同時下載，寫到預先配置好大小的part檔案。
+// 伺服器不支援Range的時候，改用單一連線下載。
+// 失敗的時候，如果伺服器有提供ETag或Last-Modified，會保留part檔案以及各段的進度，下次只下載剩下的部分。
+// 各段是同時寫入的，所以Checksum只能在全部完成之後再計算
+func (o *DownloadOptions) downloadParallel(ctx context.Context, dst, url string, v *verifier, tr *tracker) error {
+	p, err := o.probe(ctx, url)
+	if err != nil {
+		return err
+	}
+	if !p.ranges || p.size <= 0 {
+		return o.downloadFile(ctx, dst, url, v, tr)
+	}
+
+	part, meta := partPaths(dst)
+	_ = os.Remove(meta) // 不能用單一連線的方式續傳
+	var f *os.File
+	segs := loadSegments(part, p)
+	if p.validator != "" && segs != nil {
+		f, err = os.OpenFile(part, os.O_RDWR, 0644)
+	} else {
+		segs = splitSegments(p.size, int64(o.Segments))
+		f, err = os.OpenFile(part, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+		if err == nil {
+			if err = f.Truncate(p.size); err != nil { // 預先配置空間
+				_ = f.Close()
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	var downloaded int64
+	for _, seg := range segs {
+		downloaded += seg.Off - seg.Start
+	}
+	tr.begin(downloaded, p.size)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	for _, seg := range segs {
+		if seg.Off > seg.End {
+			continue // 已經完成
+		}
+		wg.Add(1)
+		go func(seg *segment) {
+			defer wg.Done()
+			if err := o.downloadSegment(ctx, f, tr, url, p.validator, seg); err != nil {
+				errOnce.Do(func() {
+					firstErr = err
+					cancel() // 其中一段失敗，其他的也沒有必要繼續
+				})
+			}
+		}(seg)
+	}
+	wg.Wait()
+
+	if err = f.Close(); firstErr == nil {
+		firstErr = err
+	}
+	if firstErr != nil {
+		if p.validator == "" || saveSegments(part, p, segs) != nil {
+			_ = os.Remove(part) // 沒有驗證碼，下次無法確認檔案是否相同
+			_ = os.Remove(segmentsPath(part))
+		}
+		return firstErr
+	}
+	return finishPart(part, meta, dst, v)
+}
+
+func saveSegments(part string, p *probe, segs []*segment) error {
+	b, err := json.Marshal(&segmentState{Validator: p.validator, Size: p.size, Segments: segs})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(segmentsPath(part), b, 0644)
+}
+
+// offsetWriter 從指定的位置開始寫入
+type offsetWriter struct {
+	w   io.WriterAt
+	off int64
+}
+
+func (ow *offsetWriter) Write(p []byte) (int, error) {
+	n, err := ow.w.WriteAt(p, ow.off)
+	ow.off += int64(n)
+	return n, err
+}
+
+// downloadSegment 下載[start, end]，失敗的時候只重試剩下的部分
+func (o *DownloadOptions) downloadSegment(ctx context.Context, f io.WriterAt, tr *tracker, url, validator string, seg *segment) error {
+	w := &offsetWriter{w: f, off: seg.Off}
+	defer func() { seg.Off = w.off }() // 記錄進度
+	var err error
+	for attempt := 0; attempt <= o.SegmentRetries; attempt++ {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err = o.fetchRange(ctx, w, tr, url, validator, seg.End); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("segment %d-%d: %w", seg.Start, seg.End, err)
+}
+
+func (o *DownloadOptions) fetchRange(ctx context.Context, w *offsetWriter, tr *tracker, url, validator string, end int64) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Range", "bytes="+strconv.FormatInt(w.off, 10)+"-"+strconv.FormatInt(end, 10))
+	if validator != "" {
+		req.Header.Set("If-Range", validator)
+	}
+	resp, err := o.client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent {
+		return newStatusError(resp) // 200表示檔案已經變了
+	}
+	if start, _, _ := parseContentRange(resp.Header.Get("Content-Range")); start != w.off {
+		return fmt.Errorf("unexpected Content-Range: %s", resp.Header.Get("Content-Range"))
+	}
+	expected := end - w.off + 1
+	n, err := io.Copy(io.MultiWriter(w, tr.writer()), io.LimitReader(o.limit(ctx, resp.Body), expected))
+	if err == nil && n != expected {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
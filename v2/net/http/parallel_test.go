@@ -0,0 +1,170 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDownloadParallel(t *testing.T) {
+	var (
+		mutex   sync.Mutex
+		ranges  []string
+		dropped bool
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rng := r.Header.Get("Range")
+		mutex.Lock()
+		if r.Method == http.MethodGet {
+			ranges = append(ranges, rng)
+		}
+		drop := !dropped && strings.HasPrefix(rng, "bytes=0-")
+		if drop {
+			dropped = true
+		}
+		mutex.Unlock()
+
+		w.Header().Set("ETag", `"v1"`)
+		if drop { // 第一段第一次只送出一部份就斷線
+			w.Header().Set("Content-Range", "bytes 0-262143/1048576")
+			w.Header().Set("Content-Length", "262144")
+			w.WriteHeader(http.StatusPartialContent)
+			_, _ = w.Write(testData[:1000])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		http.ServeContent(w, r, "data.bin", time.Time{}, bytes.NewReader(testData))
+	}))
+	defer srv.Close()
+
+	dst := filepath.Join(t.TempDir(), "data.bin")
+	if err := DownloadFileWithOptions(dst, srv.URL, &DownloadOptions{Segments: 4, SegmentRetries: 2}); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, dst, testData)
+
+	// 4段 + 1次重試，重試只需要下載剩下的部份
+	if len(ranges) != 5 {
+		t.Fatal(ranges)
+	}
+	found := false
+	for _, rng := range ranges {
+		if rng == "bytes=1000-262143" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal(ranges)
+	}
+}
+
+func TestDownloadParallel_SegmentFailed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && strings.HasPrefix(r.Header.Get("Range"), "bytes=0-") {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		http.ServeContent(w, r, "data.bin", time.Time{}, bytes.NewReader(testData))
+	}))
+	defer srv.Close()
+
+	dst := filepath.Join(t.TempDir(), "data.bin")
+	if err := DownloadFileWithOptions(dst, srv.URL, &DownloadOptions{Segments: 4, SegmentRetries: 1}); err == nil {
+		t.Fatal("must fail")
+	}
+	// 沒有ETag與Last-Modified，無法確認下次是否為相同的檔案，不保留
+	part, _ := partPaths(dst)
+	for _, p := range []string{dst, part, segmentsPath(part)} {
+		if fileExists(p) {
+			t.Fatal(p)
+		}
+	}
+}
+
+func TestDownloadParallel_Resume(t *testing.T) {
+	var (
+		mutex  sync.Mutex
+		ranges []string
+		fail   = true
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rng := r.Header.Get("Range")
+		mutex.Lock()
+		if r.Method == http.MethodGet {
+			ranges = append(ranges, rng)
+		}
+		drop := fail && strings.HasPrefix(rng, "bytes=0-")
+		mutex.Unlock()
+
+		w.Header().Set("ETag", `"v1"`)
+		if drop { // 第一段只送出一部份就斷線，其它段正常
+			w.Header().Set("Content-Range", "bytes 0-262143/1048576")
+			w.Header().Set("Content-Length", "262144")
+			w.WriteHeader(http.StatusPartialContent)
+			_, _ = w.Write(testData[:1000])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		http.ServeContent(w, r, "data.bin", time.Time{}, bytes.NewReader(testData))
+	}))
+	defer srv.Close()
+
+	dst := filepath.Join(t.TempDir(), "data.bin")
+	opts := &DownloadOptions{Segments: 4}
+	if err := DownloadFileWithOptions(dst, srv.URL, opts); err == nil {
+		t.Fatal("must fail")
+	}
+	part, _ := partPaths(dst)
+	if !fileExists(part) || !fileExists(segmentsPath(part)) {
+		t.Fatal("the part file and the progress must be kept")
+	}
+
+	mutex.Lock()
+	fail, ranges = false, nil
+	mutex.Unlock()
+	if err := (&Downloader{Options: opts}).Download(context.Background(), dst, srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, dst, testData)
+	if fileExists(segmentsPath(part)) {
+		t.Fatal("the progress must be removed")
+	}
+	// 其它段可能已經完成或只完成一部分，但第一段一定是從1000開始
+	for _, rng := range ranges {
+		if strings.HasPrefix(rng, "bytes=0-") {
+			t.Fatal("must not start over", ranges)
+		}
+	}
+	found := false
+	for _, rng := range ranges {
+		found = found || rng == "bytes=1000-262143"
+	}
+	if !found {
+		t.Fatal(ranges)
+	}
+
+	// 檔案變了或進度不完整: 重新開始
+	for _, state := range []string{
+		`{"validator":"\"v0\"","size":1048576,"segments":[{"start":0,"off":1048576,"end":1048575}]}`,
+		`{"validator":"\"v1\"","size":1048576,"segments":[]}`,
+		`{"validator":"\"v1\"","size":1048576,"segments":[{"start":0,"off":1000,"end":999}]}`,
+	} {
+		if err := os.WriteFile(segmentsPath(part), []byte(state), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(part, make([]byte, len(testData)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := DownloadFileWithOptions(dst, srv.URL, opts); err != nil {
+			t.Fatal(err)
+		}
+		assertFile(t, dst, testData)
+	}
+}
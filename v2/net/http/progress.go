@@ -0,0 +1,233 @@
+package http
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	_fmt "github.com/CarsonSlovoka/go-pkg/v2/fmt"
+)
+
+// DefaultProgressInterval is used when DownloadOptions.ProgressInterval is 0
+const DefaultProgressInterval = 500 * time.Millisecond
+
+// Progress is a snapshot of a download
+type Progress struct {
+	Downloaded int64 // 包含續傳之前已經下載的部分
+	Total      int64 // -1 表示不知道
+
+	Rate        float64 // bytes/s, 最近一個間隔的速度
+	AverageRate float64 // bytes/s, 只計算這次傳輸的部分
+	Elapsed     time.Duration
+	ETA         time.Duration // -1 表示不知道
+
+	Finished bool // 最後一次的通知，不論成功與否
+}
+
+// Percent returns 0~100, -1 if the total is unknown
+func (p Progress) Percent() float64 {
+	if p.Total <= 0 {
+		return -1
+	}
+	return float64(p.Downloaded) * 100 / float64(p.Total)
+}
+
+// ProgressFunc receives the progress at every DownloadOptions.ProgressInterval, not on every write.
+// It's called from another goroutine, and never concurrently.
+type ProgressFunc func(Progress)
+
+// tracker 統計下載的數量，定時通知ProgressFunc。nil表示不需要統計
+type tracker struct {
+	fn       ProgressFunc
+	interval time.Duration
+
+	downloaded atomic.Int64
+	total      atomic.Int64
+	initial    int64 // 開始時已經有的數量，不列入平均速度
+	start      time.Time
+
+	mu       sync.Mutex // 保證fn不會同時被呼叫
+	last     int64
+	lastTime time.Time
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func (o *DownloadOptions) newTracker() *tracker {
+	if o.Progress == nil {
+		return nil
+	}
+	interval := o.ProgressInterval
+	if interval <= 0 {
+		interval = DefaultProgressInterval
+	}
+	t := &tracker{fn: o.Progress, interval: interval}
+	t.total.Store(-1)
+	return t
+}
+
+// begin 開始計時，downloaded是已經存在的數量(續傳)。
+// 重新開始下載的時候(續傳失敗)會再被呼叫一次
+func (t *tracker) begin(downloaded, total int64) {
+	if t == nil {
+		return
+	}
+	t.downloaded.Store(downloaded)
+	t.total.Store(total)
+	t.mu.Lock()
+	t.initial, t.last = downloaded, downloaded
+	if t.stop != nil {
+		t.mu.Unlock()
+		return
+	}
+	t.start = time.Now()
+	t.lastTime = t.start
+	t.mu.Unlock()
+	t.stop, t.done = make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(t.done)
+		ticker := time.NewTicker(t.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-t.stop:
+				return
+			case <-ticker.C:
+				t.report(false)
+			}
+		}
+	}()
+}
+
+// end 停止計時並送出最後一次的通知
+func (t *tracker) end() {
+	if t == nil || t.stop == nil {
+		return
+	}
+	close(t.stop)
+	<-t.done
+	t.report(true)
+}
+
+func (t *tracker) Write(p []byte) (int, error) {
+	t.downloaded.Add(int64(len(p)))
+	return len(p), nil
+}
+
+// writer 下載的內容也要寫到這裡
+func (t *tracker) writer() io.Writer {
+	if t == nil {
+		return io.Discard
+	}
+	return t
+}
+
+func (t *tracker) report(finished bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	p := Progress{
+		Downloaded: t.downloaded.Load(),
+		Total:      t.total.Load(),
+		Elapsed:    now.Sub(t.start),
+		ETA:        -1,
+		Finished:   finished,
+	}
+	if dt := now.Sub(t.lastTime).Seconds(); dt > 0 {
+		p.Rate = float64(p.Downloaded-t.last) / dt
+	}
+	if sec := p.Elapsed.Seconds(); sec > 0 {
+		p.AverageRate = float64(p.Downloaded-t.initial) / sec
+	}
+	if p.Total >= 0 && p.AverageRate > 0 {
+		p.ETA = time.Duration(float64(p.Total-p.Downloaded) / p.AverageRate * float64(time.Second))
+	}
+	t.last, t.lastTime = p.Downloaded, now
+	t.fn(p)
+}
+
+// ProgressBar renders the progress on a terminal:
+//
+//	[==========>         ]  52.3%  5.2 MiB / 10.0 MiB  1.3 MiB/s  ETA 4s
+//
+// Use ProgressBar.Update as the DownloadOptions.Progress
+type ProgressBar struct {
+	w     io.Writer
+	width int
+
+	Bar  *_fmt.ColorPrinter // 進度條的顏色，nil表示不使用顏色
+	Text *_fmt.ColorPrinter // 其它文字的顏色
+}
+
+// NewProgressBar width is the number of the characters of the bar, 0 means 30
+func NewProgressBar(w io.Writer, width int) *ProgressBar {
+	if width <= 0 {
+		width = 30
+	}
+	return &ProgressBar{w: w, width: width}
+}
+
+// Update redraws the line, and moves to the next line when the progress is finished
+func (b *ProgressBar) Update(p Progress) {
+	var bar string
+	if percent := p.Percent(); percent >= 0 {
+		filled := int(percent / 100 * float64(b.width))
+		if filled > b.width {
+			filled = b.width
+		}
+		bar = strings.Repeat("=", filled)
+		if filled < b.width {
+			bar += ">" + strings.Repeat(" ", b.width-filled-1)
+		}
+	} else {
+		// 不知道大小的時候，顯示一個來回移動的方塊
+		pos := int(p.Elapsed/DefaultProgressInterval) % b.width
+		bar = strings.Repeat(" ", pos) + "#" + strings.Repeat(" ", b.width-pos-1)
+	}
+	if b.Bar != nil {
+		bar = b.Bar.Sprintf("%s", bar)
+	}
+
+	text := FormatBytes(p.Downloaded)
+	if p.Total >= 0 {
+		text = fmt.Sprintf("%5.1f%%  %s / %s", p.Percent(), text, FormatBytes(p.Total))
+	}
+	rate := p.Rate
+	if p.Finished {
+		rate = p.AverageRate
+	}
+	text += fmt.Sprintf("  %s/s", FormatBytes(int64(rate)))
+	if !p.Finished && p.ETA >= 0 {
+		text += "  ETA " + p.ETA.Round(time.Second).String()
+	} else if p.Finished {
+		text += "  " + p.Elapsed.Round(time.Millisecond).String()
+	}
+	if b.Text != nil {
+		text = b.Text.Sprintf("%s", text)
+	}
+
+	end := ""
+	if p.Finished {
+		end = "\n"
+	}
+	// \033[K 清除這一行剩下的文字
+	_, _ = fmt.Fprintf(b.w, "\r[%s] %s\033[K%s", bar, text, end)
+}
+
+// FormatBytes formats n with binary units, e.g. 1.5 KiB, 10.0 MiB
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
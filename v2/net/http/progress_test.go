@@ -0,0 +1,172 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	_fmt "github.com/CarsonSlovoka/go-pkg/v2/fmt"
+)
+
+// newSlowServer 每次送出chunk大小之後暫停一下，讓進度可以被觀察到
+func newSlowServer(t *testing.T, chunk int, delay time.Duration, withLength bool) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if withLength {
+			w.Header().Set("Content-Length", "1048576")
+		}
+		for i := 0; i < len(testData); i += chunk {
+			_, _ = w.Write(testData[i : i+chunk])
+			w.(http.Flusher).Flush()
+			time.Sleep(delay)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+type progressRecorder struct {
+	mu   sync.Mutex
+	list []Progress
+}
+
+func (r *progressRecorder) record(p Progress) {
+	r.mu.Lock()
+	r.list = append(r.list, p)
+	r.mu.Unlock()
+}
+
+func TestDownloadFileWithOptions_Progress(t *testing.T) {
+	srv := newSlowServer(t, len(testData)/8, 30*time.Millisecond, true)
+	dst := filepath.Join(t.TempDir(), "data.bin")
+	var r progressRecorder
+	if err := DownloadFileWithOptions(dst, srv.URL, &DownloadOptions{
+		Progress:         r.record,
+		ProgressInterval: 20 * time.Millisecond,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, dst, testData)
+
+	// 8次寫入，每次30ms，間隔20ms: 應該有好幾次，但不是每次寫入都通知
+	if len(r.list) < 3 {
+		t.Fatal("too few reports", len(r.list))
+	}
+	var last int64
+	for i, p := range r.list {
+		if p.Total != int64(len(testData)) || p.Downloaded < last {
+			t.Fatal(i, p)
+		}
+		last = p.Downloaded
+		if p.Finished != (i == len(r.list)-1) {
+			t.Fatal("only the last report is finished", i)
+		}
+	}
+	final := r.list[len(r.list)-1]
+	if final.Downloaded != final.Total || final.Percent() != 100 || final.ETA != 0 || final.AverageRate <= 0 {
+		t.Fatal(final)
+	}
+	for _, p := range r.list[:len(r.list)-1] {
+		if p.Downloaded > 0 && p.Downloaded < p.Total && p.ETA <= 0 {
+			t.Fatal("the ETA should be known", p)
+		}
+	}
+}
+
+func TestDownloadFileWithOptions_ProgressUnknownTotal(t *testing.T) {
+	srv := newSlowServer(t, len(testData)/4, 10*time.Millisecond, false)
+	dst := filepath.Join(t.TempDir(), "data.bin")
+	var r progressRecorder
+	if err := DownloadFileWithOptions(dst, srv.URL, &DownloadOptions{Progress: r.record}); err != nil {
+		t.Fatal(err)
+	}
+	final := r.list[len(r.list)-1]
+	if !final.Finished || final.Total != -1 || final.ETA != -1 || final.Percent() != -1 || final.Downloaded != int64(len(testData)) {
+		t.Fatal(final)
+	}
+}
+
+func TestDownloadFileWithOptions_ProgressResume(t *testing.T) {
+	srv, _ := newFlakyServer(t, `"v1"`)
+	dst := filepath.Join(t.TempDir(), "data.bin")
+	_ = DownloadFileWithOptions(dst, srv.URL, nil)
+
+	var r progressRecorder
+	if err := DownloadFileWithOptions(dst, srv.URL, &DownloadOptions{Progress: r.record}); err != nil {
+		t.Fatal(err)
+	}
+	final := r.list[len(r.list)-1]
+	if final.Downloaded != int64(len(testData)) || final.Total != int64(len(testData)) {
+		t.Fatal(final)
+	}
+}
+
+func TestDownloadParallel_Progress(t *testing.T) {
+	srv := newServeContentServer(t, `"v1"`)
+	dst := filepath.Join(t.TempDir(), "data.bin")
+	var r progressRecorder
+	if err := DownloadFileWithOptions(dst, srv.URL, &DownloadOptions{Segments: 4, Progress: r.record}); err != nil {
+		t.Fatal(err)
+	}
+	final := r.list[len(r.list)-1]
+	if !final.Finished || final.Downloaded != int64(len(testData)) || final.Total != int64(len(testData)) {
+		t.Fatal(final)
+	}
+}
+
+func TestProgressBar(t *testing.T) {
+	var buf bytes.Buffer
+	bar := NewProgressBar(&buf, 10)
+	bar.Update(Progress{Downloaded: 512 * 1024, Total: 1024 * 1024, Rate: 2048, ETA: 3 * time.Second})
+	if got := buf.String(); got != "\r[=====>    ]  50.0%  512.0 KiB / 1.0 MiB  2.0 KiB/s  ETA 3s\033[K" {
+		t.Fatalf("%q", got)
+	}
+
+	buf.Reset()
+	bar.Update(Progress{Downloaded: 1024 * 1024, Total: 1024 * 1024, AverageRate: 100, Elapsed: time.Second, Finished: true})
+	if got := buf.String(); got != "\r[==========] 100.0%  1.0 MiB / 1.0 MiB  100 B/s  1s\033[K\n" {
+		t.Fatalf("%q", got)
+	}
+
+	buf.Reset()
+	bar.Update(Progress{Downloaded: 10, Total: -1, ETA: -1})
+	if got := buf.String(); got != "\r[#         ] 10 B  0 B/s\033[K" {
+		t.Fatalf("%q", got)
+	}
+
+	buf.Reset()
+	bar.Bar = _fmt.NewColorPrinter(0, 0, 0, 0, 255, 0)
+	bar.Update(Progress{Downloaded: 10, Total: 10})
+	if got := buf.String(); !strings.HasPrefix(got, "\r[\u001B[48;2;0;255;0m\u001B[38;2;0;0;0m==========\u001B[0m]") {
+		t.Fatalf("%q", got)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	for n, expected := range map[int64]string{
+		0:        "0 B",
+		1023:     "1023 B",
+		1024:     "1.0 KiB",
+		1536:     "1.5 KiB",
+		10 << 20: "10.0 MiB",
+		5 << 30:  "5.0 GiB",
+		3 << 40:  "3.0 TiB",
+	} {
+		if got := FormatBytes(n); got != expected {
+			t.Fatal(n, got)
+		}
+	}
+}
+
+func ExampleProgressBar() {
+	bar := NewProgressBar(os.Stderr, 0)
+	bar.Bar = _fmt.NewColorPrinter(0, 0, 0, 0, 255, 0)
+	_ = DownloadFileWithOptions(filepath.Join(os.TempDir(), "go.zip"), "https://go.dev/dl/go1.19.windows-amd64.zip", &DownloadOptions{
+		Progress: bar.Update,
+	})
+}
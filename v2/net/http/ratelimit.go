@@ -0,0 +1,148 @@
+package http
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket that limits the bytes per second.
+// A Limiter can be shared by many downloads to limit their total bandwidth, and the rate can be changed at any time.
+// The zero value is unlimited.
+type Limiter struct {
+	mu      sync.Mutex
+	rate    float64 // bytes/s, <= 0 表示不限制
+	tokens  float64 // 可以是負數，表示已經借用了，要等到補回來
+	last    time.Time
+	changed chan struct{} // SetRate的時候關閉，讓等待中的讀取重新計算。需要的時候才建立
+}
+
+// NewLimiter bytesPerSecond <= 0 means unlimited
+func NewLimiter(bytesPerSecond int64) *Limiter {
+	return &Limiter{rate: float64(bytesPerSecond), last: time.Now()}
+}
+
+// SetRate changes the rate, the waiting reads are adjusted immediately. bytesPerSecond <= 0 means unlimited
+func (l *Limiter) SetRate(bytesPerSecond int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill(time.Now())
+	l.rate = float64(bytesPerSecond)
+	if l.rate <= 0 {
+		l.tokens = 0 // 取消所有的借用
+	}
+	if l.changed != nil {
+		close(l.changed)
+		l.changed = nil
+	}
+}
+
+// Rate returns the bytes per second, 0 means unlimited
+func (l *Limiter) Rate() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.rate <= 0 {
+		return 0
+	}
+	return int64(l.rate)
+}
+
+// refill 補充從上次到現在的額度，最多累積0.1秒，避免閒置之後瞬間超過
+func (l *Limiter) refill(now time.Time) {
+	if l.rate > 0 {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if burst := l.rate / 10; l.tokens > burst {
+			l.tokens = burst
+		}
+	}
+	l.last = now
+}
+
+// WaitN takes n bytes from the bucket and blocks until the debt is repaid or ctx is done
+func (l *Limiter) WaitN(ctx context.Context, n int) error {
+	l.mu.Lock()
+	if l.rate <= 0 {
+		l.mu.Unlock()
+		return nil
+	}
+	l.refill(time.Now())
+	l.tokens -= float64(n)
+	for {
+		if l.rate <= 0 || l.tokens >= 0 {
+			l.mu.Unlock()
+			return nil
+		}
+		wait := time.Duration(-l.tokens / l.rate * float64(time.Second))
+		if l.changed == nil {
+			l.changed = make(chan struct{})
+		}
+		changed := l.changed
+		l.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-changed:
+			timer.Stop()
+		case <-timer.C:
+		}
+		l.mu.Lock()
+		l.refill(time.Now())
+	}
+}
+
+// chunk 每次讀取的上限，速度慢的時候讀少一點，比較平順
+func (l *Limiter) chunk() int {
+	const maxChunk = 32 * 1024
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.rate <= 0 || l.rate/10 >= maxChunk {
+		return maxChunk
+	}
+	if l.rate/10 < 512 {
+		return 512
+	}
+	return int(l.rate / 10)
+}
+
+// limitedReader 讀取之後向所有的Limiter取得額度
+type limitedReader struct {
+	ctx      context.Context
+	r        io.Reader
+	limiters []*Limiter
+}
+
+func (lr *limitedReader) Read(p []byte) (int, error) {
+	size := len(p)
+	for _, l := range lr.limiters {
+		if c := l.chunk(); c < size {
+			size = c
+		}
+	}
+	n, err := lr.r.Read(p[:size])
+	if n > 0 {
+		for _, l := range lr.limiters {
+			if waitErr := l.WaitN(lr.ctx, n); waitErr != nil {
+				return n, waitErr
+			}
+		}
+	}
+	return n, err
+}
+
+// limit 依照Limiters限制讀取的速度
+func (o *DownloadOptions) limit(ctx context.Context, r io.Reader) io.Reader {
+	var limiters []*Limiter
+	for _, l := range o.Limiters {
+		if l != nil {
+			limiters = append(limiters, l)
+		}
+	}
+	if len(limiters) == 0 {
+		return r
+	}
+	return &limitedReader{ctx, r, limiters}
+}
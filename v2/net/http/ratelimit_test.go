@@ -0,0 +1,162 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLimiter_WaitN(t *testing.T) {
+	l := NewLimiter(100 * 1024)
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		if err := l.WaitN(context.Background(), 5*1024); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 50KiB, 100KiB/s
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond || elapsed > 1500*time.Millisecond {
+		t.Fatal(elapsed)
+	}
+
+	unlimited := NewLimiter(0)
+	start = time.Now()
+	if err := unlimited.WaitN(context.Background(), 1<<30); err != nil || time.Since(start) > 100*time.Millisecond {
+		t.Fatal(err)
+	}
+}
+
+func TestLimiter_SetRate(t *testing.T) {
+	l := NewLimiter(1024)
+	if l.Rate() != 1024 {
+		t.Fatal(l.Rate())
+	}
+	time.AfterFunc(50*time.Millisecond, func() { l.SetRate(0) })
+	start := time.Now()
+	if err := l.WaitN(context.Background(), 10*1024); err != nil { // 原本要10秒
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatal("the waiting read must be released", elapsed)
+	}
+	if l.Rate() != 0 {
+		t.Fatal(l.Rate())
+	}
+
+	// 從不限制改為限制
+	l.SetRate(10 * 1024)
+	start = time.Now()
+	if err := l.WaitN(context.Background(), 5*1024); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Fatal(elapsed)
+	}
+}
+
+func TestLimiter_Cancel(t *testing.T) {
+	l := NewLimiter(1024)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := l.WaitN(ctx, 10*1024); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal(err)
+	}
+}
+
+func TestDownloadFileWithOptions_Limiters(t *testing.T) {
+	srv := newServeContentServer(t, `"v1"`)
+	for _, segments := range []int{0, 4} {
+		dst := filepath.Join(t.TempDir(), "data.bin")
+		start := time.Now()
+		if err := DownloadFileWithOptions(dst, srv.URL, &DownloadOptions{
+			Segments: segments,
+			Limiters: []*Limiter{NewLimiter(2 << 20), nil},
+		}); err != nil {
+			t.Fatal(err)
+		}
+		assertFile(t, dst, testData)
+		// 1MiB, 2MiB/s
+		if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+			t.Fatal(segments, elapsed)
+		}
+	}
+}
+
+func TestDownloadFileWithOptions_GlobalLimiter(t *testing.T) {
+	srv := newServeContentServer(t, `"v1"`)
+	global := NewLimiter(4 << 20)
+	dir := t.TempDir()
+	start := time.Now()
+	var wg sync.WaitGroup
+	for _, name := range []string{"a", "b", "c", "d"} {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			if err := DownloadFileWithOptions(filepath.Join(dir, name), srv.URL, &DownloadOptions{
+				Limiters: []*Limiter{NewLimiter(0), global},
+			}); err != nil {
+				t.Error(err)
+			}
+		}(name)
+	}
+	wg.Wait()
+	// 4MiB, 4MiB/s
+	if elapsed := time.Since(start); elapsed < 800*time.Millisecond {
+		t.Fatal(elapsed)
+	}
+}
+
+func TestDownloadFileWithOptions_Unthrottle(t *testing.T) {
+	srv := newServeContentServer(t, `"v1"`)
+	l := NewLimiter(256 * 1024) // 需要4秒
+	time.AfterFunc(200*time.Millisecond, func() { l.SetRate(0) })
+	start := time.Now()
+	dst := filepath.Join(t.TempDir(), "data.bin")
+	if err := DownloadFileWithOptions(dst, srv.URL, &DownloadOptions{Limiters: []*Limiter{l}}); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatal(elapsed)
+	}
+	assertFile(t, dst, testData)
+}
+
+func TestManager_SetDownloadRate(t *testing.T) {
+	srv := newServeContentServer(t, `"v1"`)
+	dir := t.TempDir()
+	m := &Manager{DownloadRate: 256 * 1024}
+	time.AfterFunc(200*time.Millisecond, func() { m.SetDownloadRate(0) })
+	start := time.Now()
+	summary := m.Run(context.Background(),
+		Job{URL: srv.URL + "/a", Dst: filepath.Join(dir, "a")},
+		Job{URL: srv.URL + "/b", Dst: filepath.Join(dir, "b")},
+	)
+	if err := summary.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond || elapsed > 2*time.Second {
+		t.Fatal(elapsed)
+	}
+	if len(m.limiters) != 0 {
+		t.Fatal("the limiters of the finished jobs must be released")
+	}
+}
+
+func TestLimiter_ZeroValue(t *testing.T) {
+	var l Limiter
+	if err := l.WaitN(context.Background(), 1<<30); err != nil || l.Rate() != 0 {
+		t.Fatal(err)
+	}
+	l.SetRate(10 * 1024)
+	start := time.Now()
+	if err := l.WaitN(context.Background(), 5*1024); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Fatal(elapsed)
+	}
+	l.SetRate(0)
+}
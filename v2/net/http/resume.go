@@ -0,0 +1,229 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DownloadOptions 下載的選項，零值即可使用
+type DownloadOptions struct {
+	Client  *http.Client  // nil表示依照Timeout建立一個新的client
+	Timeout time.Duration // 只有在Client為nil的時候使用，0表示不限制
+
+	// Segments 大於1的時候，如果伺服器支援Range，會把檔案分成多段同時下載
+	Segments int
+	// SegmentRetries 每一段失敗的時候可以重試幾次 (只會重新下載該段剩下的部份)
+	SegmentRetries int
+
+	// Checksum, Signature 下載完成之後檢查檔案，不相符的時候會刪除檔案並回傳錯誤
+	Checksum  *Checksum
+	Signature *Signature
+
+	// Progress 定時回報下載的進度，間隔為ProgressInterval (0表示DefaultProgressInterval)
+	Progress         ProgressFunc
+	ProgressInterval time.Duration
+
+	// Cache 不是nil的時候，先使用快取的內容(或向伺服器確認是否有變更)，此時不會續傳或分段下載
+	Cache *Cache
+
+	// Limiters 限制下載的速度，每一個都要符合。
+	// 例如每個下載各自使用一個Limiter，再加上一個所有下載共用的Limiter作為整體的上限
+	Limiters []*Limiter
+}
+
+func (o *DownloadOptions) client() *http.Client {
+	if o.Client != nil {
+		return o.Client
+	}
+	return &http.Client{Timeout: o.Timeout}
+}
+
+// DownloadFileWithOptions downloads url to dst and supports resuming.
+// The data is written to "{dst}.part" first, and renamed to dst when it's completed.
+// If the download fails, the part file is kept, the next call sends "Range" with "If-Range" (ETag or Last-Modified)
+// to continue from where it stopped. If the server ignores the range or the file has changed, it starts over.
+// If opts.Segments > 1 and the server accepts ranges, the segments are downloaded concurrently instead,
+// and the progress of each segment is kept in "{dst}.part.segments" to resume them.
+// If opts.Checksum is set, the digest is computed while downloading, a *ChecksumError is returned when it doesn't match.
+// If opts.Cache is set, an unchanged file is copied from the cache instead of downloading it again.
+func DownloadFileWithOptions(dst, url string, opts *DownloadOptions) error {
+	if opts == nil {
+		opts = &DownloadOptions{}
+	}
+	return opts.download(context.Background(), dst, url)
+}
+
+func (o *DownloadOptions) download(ctx context.Context, dst, url string) error {
+	v, err := o.newVerifier(ctx, url)
+	if err != nil {
+		return err
+	}
+	tr := o.newTracker()
+	defer tr.end()
+	if o.Cache != nil {
+		return o.downloadCached(ctx, dst, url, v, tr)
+	}
+	if o.Segments > 1 {
+		return o.downloadParallel(ctx, dst, url, v, tr)
+	}
+	return o.downloadFile(ctx, dst, url, v, tr)
+}
+
+// partPaths 未完成的檔案, 以及記錄If-Range所需驗證碼的檔案
+func partPaths(dst string) (part, meta string) {
+	return dst + ".part", dst + ".part.meta"
+}
+
+// validator 取得可以放到If-Range的驗證碼，If-Range只能使用strong ETag
+func validator(h http.Header) string {
+	if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return h.Get("Last-Modified")
+}
+
+func (o *DownloadOptions) downloadFile(ctx context.Context, dst, url string, v *verifier, tr *tracker) error {
+	part, meta := partPaths(dst)
+
+	for attempt := 0; attempt < 2; attempt++ { // 第二次表示續傳失敗，從頭開始
+		var offset int64
+		var lastValidator string
+		if attempt == 0 {
+			if info, err := os.Stat(part); err == nil && info.Size() > 0 {
+				if b, err := os.ReadFile(meta); err == nil && len(b) > 0 {
+					offset, lastValidator = info.Size(), string(b)
+				}
+			}
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		if offset > 0 {
+			req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+			req.Header.Set("If-Range", lastValidator)
+		}
+
+		resp, err := o.client().Do(req)
+		if err != nil {
+			return err
+		}
+
+		var flag int
+		v.reset()
+		switch resp.StatusCode {
+		case http.StatusPartialContent:
+			if start, _, _ := parseContentRange(resp.Header.Get("Content-Range")); offset == 0 || start != offset {
+				_ = resp.Body.Close()
+				continue
+			}
+			flag = os.O_WRONLY | os.O_APPEND
+			if err = v.hashFile(part); err != nil {
+				_ = resp.Body.Close()
+				return err
+			}
+			tr.begin(offset, contentSize(resp, offset))
+		case http.StatusOK: // 伺服器不支援Range，或者檔案已經變了
+			flag = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+			offset = 0
+			_ = os.Remove(meta)
+			_ = os.Remove(segmentsPath(part)) // 分段下載的進度也不能用了
+			if v := validator(resp.Header); v != "" {
+				if err = os.WriteFile(meta, []byte(v), 0644); err != nil {
+					_ = resp.Body.Close()
+					return err
+				}
+			}
+			tr.begin(0, resp.ContentLength)
+		case http.StatusRequestedRangeNotSatisfiable:
+			_ = resp.Body.Close()
+			// part檔案已經是完整的: Content-Range: bytes */{size}
+			if _, _, size := parseContentRange(resp.Header.Get("Content-Range")); offset > 0 && size == offset {
+				tr.begin(offset, size)
+				return finishPart(part, meta, dst, v)
+			}
+			continue
+		default:
+			_ = resp.Body.Close()
+			return newStatusError(resp)
+		}
+
+		err = writePart(part, flag, io.TeeReader(o.limit(ctx, resp.Body), io.MultiWriter(v.writer(), tr.writer())))
+		_ = resp.Body.Close()
+		if err != nil {
+			return err // 保留part檔案，下次可以續傳
+		}
+		if v != nil {
+			v.hashed = true
+		}
+		return finishPart(part, meta, dst, v)
+	}
+	return errors.New("the server does not handle the range request correctly")
+}
+
+// contentSize 206的完整大小，不知道的時候回傳-1
+func contentSize(resp *http.Response, offset int64) int64 {
+	if _, _, size := parseContentRange(resp.Header.Get("Content-Range")); size > 0 {
+		return size
+	}
+	if resp.ContentLength >= 0 {
+		return offset + resp.ContentLength
+	}
+	return -1
+}
+
+func writePart(part string, flag int, body io.Reader) error {
+	f, err := os.OpenFile(part, flag, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(f, body); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// finishPart 下載完成，檢查之後把part檔案改名為正式的名稱
+func finishPart(part, meta, dst string, v *verifier) error {
+	if err := v.verify(part); err != nil {
+		_ = os.Remove(meta)
+		_ = os.Remove(segmentsPath(part))
+		return err
+	}
+	if err := os.Rename(part, dst); err != nil {
+		return err
+	}
+	_ = os.Remove(meta)
+	_ = os.Remove(segmentsPath(part))
+	return nil
+}
+
+// parseContentRange "bytes 100-199/1000", "bytes */1000". The unknown values are -1.
+func parseContentRange(s string) (start, end, size int64) {
+	start, end, size = -1, -1, -1
+	s = strings.TrimPrefix(s, "bytes ")
+	rangePart, sizePart, found := strings.Cut(s, "/")
+	if !found {
+		return
+	}
+	if v, err := strconv.ParseInt(sizePart, 10, 64); err == nil {
+		size = v
+	}
+	if first, last, ok := strings.Cut(rangePart, "-"); ok {
+		if v, err := strconv.ParseInt(first, 10, 64); err == nil {
+			start = v
+		}
+		if v, err := strconv.ParseInt(last, 10, 64); err == nil {
+			end = v
+		}
+	}
+	return
+}
@@ -0,0 +1,186 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testData 測試用的檔案內容
+var testData = bytes.Repeat([]byte("0123456789abcdef"), 64*1024) // 1MB
+
+// newFlakyServer 第一次的請求只會送出一半就斷線，之後才正常，ServeContent本身支援Range與If-Range
+func newFlakyServer(t *testing.T, etag string) (*httptest.Server, *[]string) {
+	var count int32
+	var ranges []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		w.Header().Set("ETag", etag)
+		if atomic.AddInt32(&count, 1) == 1 {
+			w.Header().Set("Content-Length", "1048576")
+			_, _ = w.Write(testData[:len(testData)/2])
+			panic(http.ErrAbortHandler) // 模擬斷線
+		}
+		http.ServeContent(w, r, "data.bin", time.Time{}, bytes.NewReader(testData))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &ranges
+}
+
+// newServeContentServer 支援Range與If-Range的伺服器
+func newServeContentServer(t *testing.T, etag string) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+		http.ServeContent(w, r, "data.bin", time.Time{}, bytes.NewReader(testData))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func assertFile(t *testing.T, path string, expected []byte) {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, expected) {
+		t.Fatalf("%s: the content is different, size %d != %d", path, len(b), len(expected))
+	}
+}
+
+func TestDownloadFileWithOptions_Resume(t *testing.T) {
+	srv, ranges := newFlakyServer(t, `"v1"`)
+	dst := filepath.Join(t.TempDir(), "data.bin")
+
+	if err := DownloadFileWithOptions(dst, srv.URL, nil); err == nil {
+		t.Fatal("the connection should be dropped")
+	}
+	part, meta := partPaths(dst)
+	info, err := os.Stat(part)
+	if err != nil || info.Size() == 0 {
+		t.Fatal("the part file must be kept", err)
+	}
+	if _, err = os.Stat(dst); !os.IsNotExist(err) {
+		t.Fatal("dst must not exist before it's completed")
+	}
+
+	if err = DownloadFileWithOptions(dst, srv.URL, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := (*ranges)[1]; got != "bytes="+strconv.FormatInt(info.Size(), 10)+"-" {
+		t.Fatal(got)
+	}
+	assertFile(t, dst, testData)
+	for _, p := range []string{part, meta} {
+		if _, err = os.Stat(p); !os.IsNotExist(err) {
+			t.Fatal(p, "must be removed")
+		}
+	}
+}
+
+// 伺服器不支援Range: 要從頭開始，而不是接在後面
+func TestDownloadFileWithOptions_IgnoreRange(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write(testData)
+	}))
+	defer srv.Close()
+
+	dst := filepath.Join(t.TempDir(), "data.bin")
+	part, meta := partPaths(dst)
+	_ = os.WriteFile(part, []byte("garbage"), 0644)
+	_ = os.WriteFile(meta, []byte(`"v1"`), 0644)
+	if err := DownloadFileWithOptions(dst, srv.URL, &DownloadOptions{Timeout: 5 * time.Second}); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, dst, testData)
+}
+
+// 檔案已經變了(ETag不同): If-Range不成立，伺服器回傳完整的內容
+func TestDownloadFileWithOptions_Changed(t *testing.T) {
+	srv := newServeContentServer(t, `"v2"`)
+	dst := filepath.Join(t.TempDir(), "data.bin")
+	part, meta := partPaths(dst)
+	_ = os.WriteFile(part, []byte("old content"), 0644)
+	_ = os.WriteFile(meta, []byte(`"v1"`), 0644)
+	if err := DownloadFileWithOptions(dst, srv.URL, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, dst, testData)
+}
+
+// part檔案已經是完整的: 416
+func TestDownloadFileWithOptions_Completed(t *testing.T) {
+	srv := newServeContentServer(t, `"v1"`)
+	dst := filepath.Join(t.TempDir(), "data.bin")
+	part, meta := partPaths(dst)
+	_ = os.WriteFile(part, testData, 0644)
+	_ = os.WriteFile(meta, []byte(`"v1"`), 0644)
+	if err := DownloadFileWithOptions(dst, srv.URL, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, dst, testData)
+}
+
+func TestDownloadFileWithOptions_BadStatus(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+	dst := filepath.Join(t.TempDir(), "data.bin")
+	if err := DownloadFileWithOptions(dst, srv.URL, nil); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Fatal(err)
+	}
+}
+
+func TestParseContentRange(t *testing.T) {
+	for _, d := range []struct {
+		s                string
+		start, end, size int64
+	}{
+		{"bytes 100-199/1000", 100, 199, 1000},
+		{"bytes 100-199/*", 100, 199, -1},
+		{"bytes */1000", -1, -1, 1000},
+		{"", -1, -1, -1},
+	} {
+		if start, end, size := parseContentRange(d.s); start != d.start || end != d.end || size != d.size {
+			t.Fatal(d.s, start, end, size)
+		}
+	}
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func TestDownloadFile_Resume(t *testing.T) {
+	srv, ranges := newFlakyServer(t, `"v1"`)
+	dst := filepath.Join(t.TempDir(), "data.bin")
+	if err := DownloadFile(dst, srv.URL); err == nil {
+		t.Fatal("the connection should be dropped")
+	}
+	if err := DownloadFileWithTimeout(dst, srv.URL, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if (*ranges)[1] == "" {
+		t.Fatal("the path form must resume")
+	}
+	assertFile(t, dst, testData)
+
+	// *os.File 直接寫入
+	f, err := os.Create(filepath.Join(t.TempDir(), "file.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err = DownloadFile(f, srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, f.Name(), testData)
+}
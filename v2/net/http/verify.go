@@ -0,0 +1,245 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+)
+
+// ErrBadSignature is returned when the ed25519 signature doesn't match the downloaded file
+var ErrBadSignature = errors.New("ed25519 signature verification failed")
+
+// Checksum is the expected digest of the download. Either Sum or URL must be set.
+type Checksum struct {
+	Algorithm string // "sha256" or "sha512", empty means it is decided by the length of the sum
+	Sum       string // hex
+
+	// URL of a checksum file in the sha256sum format: "{hex}  {name}" per line.
+	// Name is the entry to look for, the base name of the download URL by default.
+	URL  string
+	Name string
+}
+
+// Signature is a detached ed25519 signature of the whole file. Either Sig or URL must be set.
+// The file is read into memory to verify it, because ed25519 can't be computed in a streaming way.
+type Signature struct {
+	PublicKey ed25519.PublicKey
+	Sig       []byte // raw 64 bytes
+	URL       string // the URL of the raw signature file
+}
+
+// ChecksumError is returned when the digest of the downloaded file doesn't match. The output has been deleted.
+type ChecksumError struct {
+	Algorithm string
+	Expected  string
+	Actual    string
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("%s checksum mismatch: expected %s, got %s", e.Algorithm, e.Expected, e.Actual)
+}
+
+// verifier 檢查下載的檔案，nil表示不需要檢查
+type verifier struct {
+	algorithm string
+	expected  []byte
+	h         hash.Hash
+	hashed    bool // h是否已經包含了整個檔案 (一邊下載一邊計算)
+
+	publicKey ed25519.PublicKey
+	sig       []byte
+}
+
+func newHash(algorithm string) (hash.Hash, error) {
+	switch strings.ToLower(algorithm) {
+	case "sha256":
+		return sha256.New(), nil
+	case "sha512":
+		return sha512.New(), nil
+	default:
+		return nil, fmt.Errorf("unsupported checksum algorithm %q", algorithm)
+	}
+}
+
+// newVerifier 在下載之前先取得預期的值，拿不到就不用下載了
+func (o *DownloadOptions) newVerifier(ctx context.Context, rawURL string) (*verifier, error) {
+	if o.Checksum == nil && o.Signature == nil {
+		return nil, nil
+	}
+	v := &verifier{}
+
+	if c := o.Checksum; c != nil {
+		sum := c.Sum
+		if c.URL != "" {
+			name := c.Name
+			if name == "" {
+				u, err := url.Parse(rawURL)
+				if err != nil {
+					return nil, err
+				}
+				name = path.Base(u.Path)
+			}
+			content, err := o.fetch(ctx, c.URL)
+			if err != nil {
+				return nil, fmt.Errorf("checksum file: %w", err)
+			}
+			if sum, err = lookupChecksum(content, name); err != nil {
+				return nil, err
+			}
+		}
+		expected, err := hex.DecodeString(strings.TrimSpace(sum))
+		if err != nil || len(expected) == 0 {
+			return nil, fmt.Errorf("invalid checksum %q", sum)
+		}
+		v.algorithm = c.Algorithm
+		if v.algorithm == "" {
+			v.algorithm = map[int]string{sha256.Size: "sha256", sha512.Size: "sha512"}[len(expected)]
+		}
+		if v.h, err = newHash(v.algorithm); err != nil {
+			return nil, err
+		}
+		if len(expected) != v.h.Size() {
+			return nil, fmt.Errorf("the length of the %s checksum must be %d bytes, got %d", v.algorithm, v.h.Size(), len(expected))
+		}
+		v.expected = expected
+	}
+
+	if s := o.Signature; s != nil {
+		if len(s.PublicKey) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("the ed25519 public key must be %d bytes", ed25519.PublicKeySize)
+		}
+		v.publicKey, v.sig = s.PublicKey, s.Sig
+		if s.URL != "" {
+			sig, err := o.fetch(ctx, s.URL)
+			if err != nil {
+				return nil, fmt.Errorf("signature file: %w", err)
+			}
+			v.sig = sig
+		}
+	}
+	return v, nil
+}
+
+// fetch 下載小檔案到記憶體
+func (o *DownloadOptions) fetch(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := o.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, newStatusError(resp)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+// lookupChecksum 從sha256sum格式的內容找出name的值: "{hex}  {name}" or "{hex} *{name}" (binary mode)
+// 只有一行而且沒有名稱的也可以
+func lookupChecksum(content []byte, name string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+		sum, file, found := strings.Cut(line, " ")
+		if !found {
+			continue
+		}
+		file = strings.TrimPrefix(strings.TrimLeft(file, " "), "*")
+		if file == name || path.Base(file) == name {
+			return sum, nil
+		}
+	}
+	if len(lines) == 1 && !strings.Contains(lines[0], " ") {
+		return lines[0], nil
+	}
+	return "", fmt.Errorf("checksum of %q not found", name)
+}
+
+// reset 重新開始計算 (伺服器送來完整的檔案)
+func (v *verifier) reset() {
+	if v != nil && v.h != nil {
+		v.h.Reset()
+		v.hashed = false
+	}
+}
+
+// writer 下載的內容也要寫到這裡
+func (v *verifier) writer() io.Writer {
+	if v == nil || v.h == nil {
+		return io.Discard
+	}
+	return v.h
+}
+
+// hashFile 把已經存在的內容(續傳之前的部分)加進去
+func (v *verifier) hashFile(path string) error {
+	if v == nil || v.h == nil {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(v.h, f)
+	return err
+}
+
+// verify 檢查part檔案，失敗的時候會刪除
+func (v *verifier) verify(part string) error {
+	err := v.check(part)
+	if err != nil {
+		_ = os.Remove(part)
+	}
+	return err
+}
+
+// check 檢查檔案，不會刪除
+func (v *verifier) check(part string) (err error) {
+	if v == nil {
+		return nil
+	}
+	if v.h != nil {
+		if !v.hashed {
+			v.h.Reset()
+			if err = v.hashFile(part); err != nil {
+				return err
+			}
+		}
+		if actual := v.h.Sum(nil); !bytes.Equal(actual, v.expected) {
+			return &ChecksumError{v.algorithm, hex.EncodeToString(v.expected), hex.EncodeToString(actual)}
+		}
+	}
+
+	if v.publicKey != nil {
+		data, err := os.ReadFile(part)
+		if err != nil {
+			return err
+		}
+		if !ed25519.Verify(v.publicKey, data, v.sig) {
+			return ErrBadSignature
+		}
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package http
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newVerifyServer /data.bin 檔案本身, /SHA256SUMS 校驗檔, /data.bin.sig 簽名
+func newVerifyServer(t *testing.T, sums string, sig []byte) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/data.bin", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "data.bin", time.Time{}, bytes.NewReader(testData))
+	})
+	mux.HandleFunc("/SHA256SUMS", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(sums))
+	})
+	mux.HandleFunc("/data.bin.sig", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(sig)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestDownloadFileWithOptions_Checksum(t *testing.T) {
+	srv := newVerifyServer(t, "", nil)
+	sum512 := sha512.Sum512(testData)
+	for _, test := range []struct {
+		name string
+		opts *DownloadOptions
+	}{
+		{"sha256", &DownloadOptions{Checksum: &Checksum{Sum: sha256Hex(testData)}}},
+		{"sha512", &DownloadOptions{Checksum: &Checksum{Algorithm: "sha512", Sum: hex.EncodeToString(sum512[:])}}},
+		{"parallel", &DownloadOptions{Segments: 4, Checksum: &Checksum{Sum: sha256Hex(testData)}}},
+	} {
+		dst := filepath.Join(t.TempDir(), "data.bin")
+		if err := DownloadFileWithOptions(dst, srv.URL+"/data.bin", test.opts); err != nil {
+			t.Fatal(test.name, err)
+		}
+		assertFile(t, dst, testData)
+	}
+}
+
+func TestDownloadFileWithOptions_ChecksumMismatch(t *testing.T) {
+	srv := newVerifyServer(t, "", nil)
+	wrong := sha256Hex([]byte("other"))
+	for _, segments := range []int{0, 4} {
+		dst := filepath.Join(t.TempDir(), "data.bin")
+		err := DownloadFileWithOptions(dst, srv.URL+"/data.bin", &DownloadOptions{
+			Segments: segments,
+			Checksum: &Checksum{Algorithm: "sha256", Sum: wrong},
+		})
+		var checksumErr *ChecksumError
+		if !errors.As(err, &checksumErr) {
+			t.Fatal(segments, err)
+		}
+		if checksumErr.Expected != wrong || checksumErr.Actual != sha256Hex(testData) {
+			t.Fatal(checksumErr)
+		}
+		part, meta := partPaths(dst)
+		if fileExists(dst) || fileExists(part) || fileExists(meta) {
+			t.Fatal("the output must be deleted")
+		}
+	}
+}
+
+func TestDownloadFileWithOptions_ChecksumResume(t *testing.T) {
+	srv, _ := newFlakyServer(t, `"v1"`)
+	dst := filepath.Join(t.TempDir(), "data.bin")
+	opts := &DownloadOptions{Checksum: &Checksum{Sum: sha256Hex(testData)}}
+	if err := DownloadFileWithOptions(dst, srv.URL, opts); err == nil {
+		t.Fatal("the connection should be dropped")
+	}
+	// 續傳時，之前已經下載的部分也要算進去
+	if err := DownloadFileWithOptions(dst, srv.URL, opts); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, dst, testData)
+}
+
+func TestDownloadFileWithOptions_ChecksumURL(t *testing.T) {
+	sums := "# comment\n" +
+		sha256Hex([]byte("x")) + "  other.bin\n" +
+		sha256Hex(testData) + " *data.bin\n"
+	srv := newVerifyServer(t, sums, nil)
+
+	dst := filepath.Join(t.TempDir(), "data.bin")
+	if err := DownloadFileWithOptions(dst, srv.URL+"/data.bin", &DownloadOptions{
+		Checksum: &Checksum{URL: srv.URL + "/SHA256SUMS"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, dst, testData)
+
+	// 指定其它的名稱
+	dst = filepath.Join(t.TempDir(), "data.bin")
+	var checksumErr *ChecksumError
+	if err := DownloadFileWithOptions(dst, srv.URL+"/data.bin", &DownloadOptions{
+		Checksum: &Checksum{URL: srv.URL + "/SHA256SUMS", Name: "other.bin"},
+	}); !errors.As(err, &checksumErr) {
+		t.Fatal(err)
+	}
+
+	// 找不到的時候不需要下載
+	if err := DownloadFileWithOptions(dst, srv.URL+"/data.bin", &DownloadOptions{
+		Checksum: &Checksum{URL: srv.URL + "/SHA256SUMS", Name: "unknown.bin"},
+	}); err == nil || fileExists(dst) {
+		t.Fatal("it should fail before downloading", err)
+	}
+}
+
+func TestDownloadFileWithOptions_InvalidChecksum(t *testing.T) {
+	srv := newVerifyServer(t, "", nil)
+	dst := filepath.Join(t.TempDir(), "data.bin")
+	for _, c := range []*Checksum{
+		{Sum: "xyz"},
+		{Sum: "abcd"}, // 長度無法判斷演算法
+		{Algorithm: "md5", Sum: sha256Hex(testData)},
+		{Algorithm: "sha512", Sum: sha256Hex(testData)},
+	} {
+		if err := DownloadFileWithOptions(dst, srv.URL+"/data.bin", &DownloadOptions{Checksum: c}); err == nil {
+			t.Fatal(c)
+		}
+	}
+}
+
+func TestDownloadFileWithOptions_Signature(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := ed25519.Sign(privateKey, testData)
+	srv := newVerifyServer(t, "", sig)
+
+	for _, s := range []*Signature{
+		{PublicKey: publicKey, Sig: sig},
+		{PublicKey: publicKey, URL: srv.URL + "/data.bin.sig"},
+	} {
+		dst := filepath.Join(t.TempDir(), "data.bin")
+		if err = DownloadFileWithOptions(dst, srv.URL+"/data.bin", &DownloadOptions{Signature: s}); err != nil {
+			t.Fatal(err)
+		}
+		assertFile(t, dst, testData)
+	}
+
+	otherKey, _, _ := ed25519.GenerateKey(nil)
+	dst := filepath.Join(t.TempDir(), "data.bin")
+	if err = DownloadFileWithOptions(dst, srv.URL+"/data.bin", &DownloadOptions{
+		Signature: &Signature{PublicKey: otherKey, Sig: sig},
+	}); !errors.Is(err, ErrBadSignature) {
+		t.Fatal(err)
+	}
+	if fileExists(dst) {
+		t.Fatal("the output must be deleted")
+	}
+}
+
+func TestLookupChecksum(t *testing.T) {
+	for _, test := range []struct {
+		content, name, expected string
+	}{
+		{"aa  a.bin\nbb  b.bin\n", "b.bin", "bb"},
+		{"aa *dir/a.bin\n", "a.bin", "aa"},
+		{"cc\n", "any", "cc"},
+	} {
+		if got, err := lookupChecksum([]byte(test.content), test.name); err != nil || got != test.expected {
+			t.Fatal(test, got, err)
+		}
+	}
+	if _, err := lookupChecksum([]byte("aa  a.bin\n"), "b.bin"); err == nil {
+		t.Fatal("must not be found")
+	}
+}